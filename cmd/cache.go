@@ -0,0 +1,94 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the dry run cache",
+	Long: `Inspect or clear the dry run cache.
+
+Successful dry runs are cached by the hash of the compiled SQL plus the
+BigQuery project and location, so re-running dryRun only hits BigQuery
+for models whose SQL changed. Configure with cache-dir and cache-ttl.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show dry run cache statistics",
+	Args:  cobra.NoArgs,
+	RunE:  cacheStatsRun,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached dry run results",
+	Args:  cobra.NoArgs,
+	RunE:  cacheClearRun,
+}
+
+func cacheStatsRun(cmd *cobra.Command, args []string) error {
+	cache, err := core.NewDryRunCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return fmt.Errorf("error reading cache: %w", err)
+	}
+
+	summary := fmt.Sprintf(
+		"Location: %s\n"+
+			"TTL: %s\n"+
+			"Entries: %d\n"+
+			"Expired: %d\n"+
+			"Size on disk: %s",
+		cache.Dir,
+		cache.TTL,
+		stats.Entries,
+		stats.Expired,
+		core.FormatBytes(stats.Size),
+	)
+	if stats.Entries > 0 {
+		summary += fmt.Sprintf("\nOldest: %s\nNewest: %s",
+			stats.Oldest.Format(time.DateTime), stats.Newest.Format(time.DateTime))
+	}
+
+	core.PrintBox("Dry Run Cache", summary, core.BoxRounded, core.BrightCyan)
+	return nil
+}
+
+func cacheClearRun(cmd *cobra.Command, args []string) error {
+	cache, err := core.NewDryRunCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		return fmt.Errorf("error reading cache: %w", err)
+	}
+
+	if err := cache.Clear(); err != nil {
+		return fmt.Errorf("error clearing cache: %w", err)
+	}
+
+	core.ColorPrint(core.Bold+core.Green, "✓ ")
+	fmt.Printf("Removed %d cached results from %s\n", stats.Entries, cache.Dir)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
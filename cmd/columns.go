@@ -0,0 +1,195 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"context"
+	"dibbity/core"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	columnsLive    bool
+	columnsCompile bool
+	columnsNoCache bool
+)
+
+var columnsCmd = &cobra.Command{
+	Use:   "columns [model]",
+	Short: "List a model's columns from its docs, dry run and live table",
+	Long: `List a model's columns, merged from three sources:
+
+  - the documented columns in schema.yml (via target/manifest.json)
+  - the result schema of a BigQuery dry run of the compiled SQL
+  - with --live, the existing table's INFORMATION_SCHEMA
+
+and flag columns that are undocumented, or documented but missing from the output.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: columnsRun,
+}
+
+func columnsRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	if columnsCompile {
+		opts := core.DbtOptions{Select: []string{name}, Compile: true, StatePath: viper.GetString("state-path")}
+		if err := core.CompileModel(cmd.Context(), opts, dbtDir, isVerbose); err != nil {
+			return fmt.Errorf("error compiling %s: %w", name, err)
+		}
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var cache *core.DryRunCache
+	if !columnsNoCache {
+		if cache, err = core.NewDryRunCache(); err != nil {
+			return err
+		}
+	}
+
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+	fields, err := dryRunSchema(cmd.Context(), manifest, name, dbtDir, cache, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var live []core.SchemaField
+	if columnsLive {
+		project, dataset, table := nodeRelation(node)
+		live, err = core.LiveColumns(cmd.Context(), project, dataset, table, isVerbose)
+		if err != nil {
+			return fmt.Errorf("error reading live columns of %s: %w", name, err)
+		}
+	}
+
+	merged := core.MergeColumns(node.Columns, fields, live)
+
+	headers := []string{"COLUMN", "TYPE", "DOCUMENTED TYPE"}
+	if columnsLive {
+		headers = append(headers, "LIVE TYPE")
+	}
+	headers = append(headers, "STATUS", "DESCRIPTION")
+
+	var rows [][]string
+	undocumented, missing := 0, 0
+	for _, c := range merged {
+		row := []string{c.Name, c.DryRunType, c.DocType}
+		if columnsLive {
+			row = append(row, c.LiveType)
+		}
+
+		var status string
+		switch {
+		case c.Documented && !c.InDryRun:
+			status = core.Red + "missing from output" + core.Reset
+			missing++
+		case !c.Documented:
+			status = core.Yellow + "undocumented" + core.Reset
+			undocumented++
+		default:
+			status = core.Green + "✓" + core.Reset
+		}
+		if columnsLive && c.InDryRun && !c.InLive {
+			status += core.Dim + " (not in table yet)" + core.Reset
+		}
+		row = append(row, status, strings.ReplaceAll(c.Description, "\n", " "))
+		rows = append(rows, row)
+	}
+
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("Columns of %s", name))
+	core.ColorPrintln(core.Dim, node.OriginalFilePath)
+	fmt.Println()
+	core.PrintTable(headers, rows, 60)
+	fmt.Println()
+	fmt.Printf("%d columns, %s%d undocumented%s, %s%d documented but missing%s\n",
+		len(merged),
+		core.Yellow, undocumented, core.Reset,
+		core.Red, missing, core.Reset)
+	return nil
+}
+
+// dryRunSchema dry runs a model's compiled SQL and returns its result schema.
+// Without a manifest the compiled SQL is searched for under target/compiled.
+func dryRunSchema(ctx context.Context, manifest *core.Manifest, name string, dbtDir string, cache *core.DryRunCache, b bool) ([]core.SchemaField, error) {
+	runner, err := dryRunModel(ctx, manifest, name, dbtDir, cache, b)
+	if err != nil {
+		return nil, err
+	}
+	return core.ParseDryRunSchema(runner.Out)
+}
+
+// dryRunModel dry runs a model's compiled SQL, failing if BigQuery rejects it
+func dryRunModel(ctx context.Context, manifest *core.Manifest, name string, dbtDir string, cache *core.DryRunCache, b bool) (*core.BqRunner, error) {
+	fp, isStale, err := resolveModelSQL(manifest, name, dbtDir, artefactCompiled, b)
+	if err != nil {
+		return nil, fmt.Errorf("error finding compiled SQL for model %s (try --compile): %w", name, err)
+	}
+	if isStale {
+		fmt.Fprintln(os.Stderr, core.Yellow+fmt.Sprintf("⚠ compiled SQL of %s is older than the source, pass --compile to refresh it", name)+core.Reset)
+	}
+
+	sql, err := core.LoadSQL(fp, b)
+	if err != nil {
+		return nil, fmt.Errorf("error loading SQL for model %s: %w", name, err)
+	}
+
+	runner := core.BqRunner{Query: sql, Ok: true}
+	if _, err := runner.BqDryRunCached(ctx, cache, b); err != nil {
+		return nil, fmt.Errorf("error running dry run: %w", err)
+	}
+	if !runner.Ok {
+		return nil, &core.BqError{Msg: fmt.Sprintf("dry run of %s failed: %s", name, strings.TrimSpace(runner.RespError))}
+	}
+
+	return &runner, nil
+}
+
+// nodeRelation returns the project, dataset and table a model builds into
+func nodeRelation(node *core.Node) (string, string, string) {
+	project := node.Database
+	if project == "" {
+		project = viper.GetString("bq-project")
+	}
+	table := node.Alias
+	if table == "" {
+		table = node.Name
+	}
+	return project, node.Schema, table
+}
+
+func init() {
+	rootCmd.AddCommand(columnsCmd)
+
+	columnsCmd.Flags().BoolVar(&columnsLive, "live", false, "Also read the existing table's columns from INFORMATION_SCHEMA (runs a metadata query)")
+	columnsCmd.Flags().BoolVarP(&columnsCompile, "compile", "c", false, "Compile the model first")
+	columnsCmd.Flags().BoolVar(&columnsNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	registerModelCompletion(columnsCmd, false)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var installCompletion bool
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate or install shell completion",
+	Long: `Generate the completion script for bash, zsh or fish.
+
+Completion covers commands, flags, model names and dbt selector methods
+(tag:, path:, source:) read from the dbt manifest.
+
+Load it for the current session:
+
+  bash:  source <(dibbity completion bash)
+  zsh:   source <(dibbity completion zsh)
+  fish:  dibbity completion fish | source
+
+Or install it permanently with --install (the shell defaults to $SHELL).`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE:      completionRun,
+}
+
+func completionRun(cmd *cobra.Command, args []string) error {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if len(args) == 1 {
+		shell = args[0]
+	}
+
+	if !installCompletion {
+		return writeCompletion(shell, os.Stdout)
+	}
+
+	path, err := completionInstallPath(shell)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating completion directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error writing completion script: %w", err)
+	}
+	defer f.Close()
+
+	if err := writeCompletion(shell, f); err != nil {
+		return err
+	}
+
+	core.ColorPrint(core.Bold+core.Green, "✓ ")
+	fmt.Printf("Installed %s completion to %s\n", shell, path)
+	if shell == "zsh" {
+		core.ColorPrintln(core.Dim, fmt.Sprintf("  make sure %s is in your $fpath and compinit is called in ~/.zshrc", filepath.Dir(path)))
+	}
+	fmt.Println("Restart your shell to pick it up.")
+	return nil
+}
+
+func writeCompletion(shell string, f *os.File) error {
+	switch shell {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(f, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(f)
+	case "fish":
+		return rootCmd.GenFishCompletion(f, true)
+	default:
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+}
+
+// completionInstallPath returns the per-user location each shell loads completions from
+func completionInstallPath(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", &core.ConfigError{Msg: "failed to get user home directory", Err: err}
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+
+	switch shell {
+	case "bash":
+		return filepath.Join(dataHome, "bash-completion", "completions", "dibbity"), nil
+	case "zsh":
+		dir := os.Getenv("ZDOTDIR")
+		if dir == "" {
+			dir = home
+		}
+		return filepath.Join(dir, ".zfunc", "_dibbity"), nil
+	case "fish":
+		return filepath.Join(configHome, "fish", "completions", "dibbity.fish"), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+}
+
+// selectorMethods are the dbt selector methods we can complete values for
+var selectorMethods = []string{"tag:", "path:", "source:"}
+
+// registerModelCompletion completes model names for positional args and the
+// --select flag. With selectors, dbt selector methods and graph operators
+// (+model, model+) are completed too.
+func registerModelCompletion(cmd *cobra.Command, selectors bool) {
+	complete := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeModels(toComplete, selectors)
+	}
+	cmd.ValidArgsFunction = complete
+	if cmd.Flags().Lookup("select") != nil {
+		_ = cmd.RegisterFlagCompletionFunc("select", complete)
+	}
+}
+
+func completeModels(toComplete string, selectors bool) ([]string, cobra.ShellCompDirective) {
+	dbtDir, err := core.GetFolder(false)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	manifest, err := core.LoadManifest(dbtDir, false)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var candidates []string
+	directive := cobra.ShellCompDirectiveNoFileComp
+
+	// keep graph operators like "+", "2+" and "@" in front of whatever we complete
+	prefix, word := "", toComplete
+	if selectors {
+		i := strings.IndexFunc(word, func(r rune) bool { return !strings.ContainsRune("+@0123456789", r) })
+		if i > 0 {
+			prefix, word = word[:i], word[i:]
+		}
+	}
+
+	method, _, hasMethod := strings.Cut(word, ":")
+	switch {
+	case selectors && hasMethod && method == "tag":
+		for _, t := range manifestTags(manifest) {
+			candidates = append(candidates, "tag:"+t)
+		}
+	case selectors && hasMethod && method == "path":
+		for _, p := range manifestPaths(manifest) {
+			candidates = append(candidates, "path:"+p)
+		}
+		directive |= cobra.ShellCompDirectiveNoSpace // let the user keep descending into folders
+	case selectors && hasMethod && method == "source":
+		for _, s := range manifestSources(manifest) {
+			candidates = append(candidates, "source:"+s)
+		}
+	default:
+		for _, n := range manifest.Models() {
+			candidates = append(candidates, n.Name+"\t"+n.Config.Materialized)
+		}
+		if selectors && !hasMethod {
+			candidates = append(candidates, selectorMethods...)
+			if word != "" && isMethodPrefix(word) {
+				directive |= cobra.ShellCompDirectiveNoSpace
+			}
+		}
+	}
+
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			out = append(out, prefix+c)
+		}
+	}
+	return out, directive
+}
+
+func isMethodPrefix(word string) bool {
+	for _, m := range selectorMethods {
+		if strings.HasPrefix(m, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestTags returns every tag used by a model, sorted
+func manifestTags(m *core.Manifest) []string {
+	seen := map[string]bool{}
+	for _, n := range m.Models() {
+		for _, t := range n.Tags {
+			seen[t] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// manifestPaths returns every model file and the folders containing them
+func manifestPaths(m *core.Manifest) []string {
+	seen := map[string]bool{}
+	for _, n := range m.Models() {
+		if n.PackageName != m.Metadata.ProjectName {
+			continue
+		}
+		p := filepath.ToSlash(n.OriginalFilePath)
+		seen[p] = true
+		for dir := filepath.ToSlash(filepath.Dir(p)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			seen[dir+"/"] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// manifestSources returns source names and source.table pairs
+func manifestSources(m *core.Manifest) []string {
+	seen := map[string]bool{}
+	for _, s := range m.Sources {
+		seen[s.SourceName] = true
+		seen[s.SourceName+"."+s.Name] = true
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+
+	completionCmd.Flags().BoolVar(&installCompletion, "install", false, "Install the completion script for the shell")
+}
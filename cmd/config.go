@@ -0,0 +1,117 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or validate the effective configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration and where each value came from",
+	Args:  cobra.NoArgs,
+	RunE:  configShowRun,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration, dbt project and tools",
+	Args:  cobra.NoArgs,
+	RunE:  configValidateRun,
+}
+
+// envVarFor returns the environment variable viper reads for key
+func envVarFor(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// configSource explains where the effective value of key came from
+func configSource(cmd *cobra.Command, key string) string {
+	var f *pflag.Flag
+	if f = cmd.Flags().Lookup(key); f == nil {
+		f = cmd.InheritedFlags().Lookup(key)
+	}
+	switch {
+	case f != nil && f.Changed:
+		return "flag --" + key
+	case os.Getenv(envVarFor(key)) != "":
+		return "env " + envVarFor(key)
+	}
+	for i := len(configLayers) - 1; i >= 0; i-- {
+		if configLayers[i].v.InConfig(key) {
+			return configLayers[i].path
+		}
+	}
+	if key == "project" && core.ActiveProject() != "" {
+		return workingDirLayer
+	}
+	return "default"
+}
+
+func configShowRun(cmd *cobra.Command, args []string) error {
+	width := 0
+	for _, k := range core.ConfigKeys {
+		width = max(width, len(k.Key))
+	}
+
+	var lines []string
+	for _, k := range core.ConfigKeys {
+		value := viper.GetString(k.Key)
+		if !viper.IsSet(k.Key) && value == "" {
+			value = k.Default
+		}
+		if k.Key == "project" && core.ActiveProject() != "" {
+			value = core.ActiveProject()
+		}
+		if k.Key == "dbt-dir" {
+			if dbtDir, err := core.GetFolder(false); err == nil {
+				value = dbtDir
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%-*s  %s%-30s%s %s(%s)%s",
+			width, k.Key,
+			core.Bold, value, core.Reset,
+			core.Dim, configSource(cmd, k.Key), core.Reset))
+	}
+
+	core.PrintBox("Effective Configuration", strings.Join(lines, "\n"), core.BoxRounded, core.BrightCyan)
+	return nil
+}
+
+func configValidateRun(cmd *cobra.Command, args []string) error {
+	checks := validateConfig()
+	printChecks(checks)
+
+	failed := 0
+	for _, c := range checks {
+		if !c.ok {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &core.ConfigError{Msg: fmt.Sprintf("%d problem(s) found", failed)}
+	}
+
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.Green, "Configuration looks good!")
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
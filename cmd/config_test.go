@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		home  string // ~/.dibbity.yaml
+		local string // the dbt project's .dibbity.yaml
+		env   string
+		flag  string
+		want  string
+	}{
+		{name: "default", want: "target_prod"},
+		{name: "home config", home: "state-path: home\n", want: "home"},
+		{name: "project config beats home", home: "state-path: home\n", local: "state-path: local\n", want: "local"},
+		{name: "env beats project config", home: "state-path: home\n", local: "state-path: local\n", env: "env", want: "env"},
+		{name: "flag beats env", home: "state-path: home\n", local: "state-path: local\n", env: "env", flag: "flag", want: "flag"},
+		{name: "flag beats home config", home: "state-path: home\n", flag: "flag", want: "flag"},
+		{name: "env beats default", env: "env", want: "env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, project := configEnv(t)
+			if tt.home != "" {
+				writeFile(t, filepath.Join(home, ".dibbity.yaml"), tt.home)
+			}
+			if tt.local != "" {
+				writeFile(t, filepath.Join(project, ".dibbity.yaml"), tt.local)
+			}
+			if tt.env != "" {
+				t.Setenv("DIBBITY_STATE_PATH", tt.env)
+			}
+			t.Chdir(project)
+			resetConfig(t)
+			if tt.flag != "" {
+				if err := rootCmd.PersistentFlags().Set("state-path", tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			initConfig()
+
+			if got := viper.GetString("state-path"); got != tt.want {
+				t.Fatalf("state-path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDbtDirPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		inside bool
+		env    bool
+		want   string // "project" for the dbt project found from the working directory
+	}{
+		{name: "home config outside a project", want: "/from/home"},
+		{name: "working directory beats home config", inside: true, want: "project"},
+		{name: "env beats working directory", inside: true, env: true, want: "/from/env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, project := configEnv(t)
+			writeFile(t, filepath.Join(home, ".dibbity.yaml"), "dbt-dir: /from/home\n")
+			if tt.env {
+				t.Setenv("DIBBITY_DBT_DIR", "/from/env")
+			}
+			if tt.inside {
+				t.Chdir(project)
+			} else {
+				t.Chdir(home)
+			}
+			resetConfig(t)
+
+			initConfig()
+
+			want := tt.want
+			if want == "project" {
+				want = project
+			}
+			if got := viper.GetString("dbt-dir"); got != want {
+				t.Fatalf("dbt-dir = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	home, project := configEnv(t)
+	writeFile(t, filepath.Join(home, ".dibbity.yaml"), "runner: uv\nprojects:\n  warehouse:\n    dbt-dir: "+project+"\n    state-path: home-project\n    bq-project: home-p\n")
+	writeFile(t, filepath.Join(project, ".dibbity.yaml"), "state-path: local\n")
+	t.Chdir(project)
+	resetConfig(t)
+
+	initConfig()
+
+	if configErr != nil {
+		t.Fatalf("configErr = %v", configErr)
+	}
+	want := []struct {
+		path string
+		file bool
+		key  string
+	}{
+		{filepath.Join(home, ".dibbity.yaml"), true, "runner"},
+		{workingDirLayer, false, "dbt-dir"},
+		{"projects.warehouse", false, "state-path"},
+		{filepath.Join(project, ".dibbity.yaml"), true, "state-path"},
+	}
+	if len(configLayers) != len(want) {
+		t.Fatalf("got %d layers, want %d: %+v", len(configLayers), len(want), configLayers)
+	}
+	for i, w := range want {
+		l := configLayers[i]
+		if l.path != w.path || l.file != w.file || !l.v.IsSet(w.key) {
+			t.Errorf("layer %d = %s (file %t), want %s (file %t) setting %s", i, l.path, l.file, w.path, w.file, w.key)
+		}
+	}
+
+	// set by both the home config's project and the project's own file
+	if got := viper.GetString("state-path"); got != "local" {
+		t.Errorf("state-path = %q, want the project-local %q", got, "local")
+	}
+	if got := configSource(rootCmd, "state-path"); got != filepath.Join(project, ".dibbity.yaml") {
+		t.Errorf("state-path comes from %q, want the project-local file", got)
+	}
+	if got := viper.GetString("bq-project"); got != "home-p" {
+		t.Errorf("bq-project = %q, want the project's %q", got, "home-p")
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	configEnv(t)
+	resetConfig(t)
+
+	for _, key := range []string{"dbt-dir", "runner", "bq-project", "bq-retries", "cache-ttl", "lookml-dir"} {
+		if def := rootCmd.PersistentFlags().Lookup(key).DefValue; viper.GetString(key) != def {
+			t.Errorf("%s = %q, want the flag default %q", key, viper.GetString(key), def)
+		}
+	}
+
+	flags := map[string]string{
+		"dbt-dir":    "/flag/dbt",
+		"runner":     "uv",
+		"bq-retries": "7",
+		"cache-ttl":  "1h0m0s",
+	}
+	for key, value := range flags {
+		t.Setenv(envVarFor(key), "from-env")
+		if err := rootCmd.PersistentFlags().Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	initConfig()
+	for key, value := range flags {
+		if got := viper.GetString(key); got != value {
+			t.Errorf("%s = %q, want the flag value %q", key, got, value)
+		}
+	}
+	if viper.IsSet("config") {
+		t.Error("the config flag should not be bound to a viper key")
+	}
+}
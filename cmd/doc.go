@@ -0,0 +1,303 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var docMarkdown bool
+
+var docCmd = &cobra.Command{
+	Use:   "doc [model]",
+	Short: "Show a model's documentation",
+	Long: `Show a model's documentation from target/manifest.json: description,
+owner and meta, materialization, tags, columns with their descriptions and
+tests, upstream and downstream nodes and the files it comes from.
+
+--markdown prints the same as Markdown, for pasting into tickets and PRs.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: docRun,
+}
+
+// modelDoc is everything `doc` shows about a model, ready to render
+type modelDoc struct {
+	Name         string
+	Description  string
+	Materialized string
+	Relation     string
+	Owner        string
+	Tags         []string
+	Meta         [][2]string
+	Columns      []columnDoc
+	ModelTests   []string
+	Upstream     []string
+	Downstream   []string
+	Files        [][2]string
+}
+
+type columnDoc struct {
+	Name        string
+	DataType    string
+	Description string
+	Tests       []string
+}
+
+func docRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+
+	doc := buildModelDoc(manifest, node, dbtDir)
+	if docMarkdown {
+		fmt.Print(renderDocMarkdown(doc))
+	} else {
+		renderDocTerminal(doc)
+	}
+	return nil
+}
+
+func buildModelDoc(m *core.Manifest, n *core.Node, dbtDir string) modelDoc {
+	doc := modelDoc{
+		Name:         n.Name,
+		Description:  strings.TrimSpace(n.Description),
+		Materialized: n.Config.Materialized,
+		Relation:     strings.ReplaceAll(n.RelationName, "`", ""),
+		Tags:         n.AllTags(),
+	}
+
+	meta := n.AllMeta()
+	if owner, ok := meta["owner"]; ok {
+		doc.Owner = fmt.Sprint(owner)
+		delete(meta, "owner")
+	}
+	for _, k := range sortedKeys(meta) {
+		doc.Meta = append(doc.Meta, [2]string{k, fmt.Sprint(meta[k])})
+	}
+
+	testsByColumn := map[string][]string{}
+	for _, t := range m.Tests(n.UniqueID) {
+		if t.ColumnName == "" {
+			doc.ModelTests = append(doc.ModelTests, t.TestName())
+			continue
+		}
+		key := strings.ToLower(t.ColumnName)
+		testsByColumn[key] = append(testsByColumn[key], t.TestName())
+	}
+
+	for _, key := range sortedKeys(n.Columns) {
+		c := n.Columns[key]
+		name := c.Name
+		if name == "" {
+			name = key
+		}
+		doc.Columns = append(doc.Columns, columnDoc{
+			Name:        name,
+			DataType:    c.DataType,
+			Description: strings.TrimSpace(c.Description),
+			Tests:       testsByColumn[strings.ToLower(name)],
+		})
+	}
+
+	for _, p := range m.Parents(n.UniqueID) {
+		doc.Upstream = append(doc.Upstream, fmt.Sprintf("%s (%s)", p.DisplayName(), p.ResourceType))
+	}
+	for _, c := range m.Children(n.UniqueID) {
+		doc.Downstream = append(doc.Downstream, fmt.Sprintf("%s (%s)", c.DisplayName(), c.ResourceType))
+	}
+
+	doc.Files = append(doc.Files, [2]string{"SQL", n.OriginalFilePath})
+	if yml := n.PatchFile(dbtDir); yml != "" {
+		if rel, err := filepath.Rel(dbtDir, yml); err == nil {
+			yml = rel
+		}
+		doc.Files = append(doc.Files, [2]string{"YAML", yml})
+	}
+	if n.CompiledPath != "" {
+		doc.Files = append(doc.Files, [2]string{"Compiled", n.CompiledPath})
+	}
+	return doc
+}
+
+func renderDocTerminal(doc modelDoc) {
+	var lines []string
+	if doc.Description != "" {
+		lines = append(lines, wrapText(doc.Description, 80)...)
+	} else {
+		lines = append(lines, core.Dim+"(no description)"+core.Reset)
+	}
+	lines = append(lines, "")
+	field := func(label, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%s%s:%s %s", core.Bold, label, core.Reset, value))
+		}
+	}
+	field("Materialized", doc.Materialized)
+	field("Relation", doc.Relation)
+	field("Owner", doc.Owner)
+	field("Tags", strings.Join(doc.Tags, ", "))
+	for _, kv := range doc.Meta {
+		field("Meta "+kv[0], kv[1])
+	}
+	field("Model tests", strings.Join(doc.ModelTests, ", "))
+
+	fmt.Println()
+	core.PrintBox(doc.Name, strings.Join(lines, "\n"), core.BoxRounded, core.BrightCyan)
+
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("Columns (%d)", len(doc.Columns)))
+	if len(doc.Columns) == 0 {
+		core.ColorPrintln(core.Dim, "  none documented")
+	} else {
+		var rows [][]string
+		for _, c := range doc.Columns {
+			desc := strings.ReplaceAll(c.Description, "\n", " ")
+			if desc == "" {
+				desc = core.Yellow + "undocumented" + core.Reset
+			}
+			rows = append(rows, []string{c.Name, c.DataType, strings.Join(c.Tests, ", "), desc})
+		}
+		core.PrintTable([]string{"COLUMN", "TYPE", "TESTS", "DESCRIPTION"}, rows, 70)
+	}
+
+	list := func(title string, items []string) {
+		fmt.Println()
+		core.ColorPrintln(core.Bold+core.BrightBlue, title)
+		if len(items) == 0 {
+			core.ColorPrintln(core.Dim, "  none")
+		}
+		for _, item := range items {
+			fmt.Println("  " + item)
+		}
+	}
+	list("Upstream", doc.Upstream)
+	list("Downstream", doc.Downstream)
+
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, "Files")
+	for _, f := range doc.Files {
+		fmt.Printf("  %-9s %s\n", f[0], f[1])
+	}
+}
+
+func renderDocMarkdown(doc modelDoc) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# `%s`\n\n", doc.Name)
+	if doc.Description != "" {
+		fmt.Fprintf(&sb, "%s\n\n", doc.Description)
+	}
+
+	var props [][2]string
+	add := func(label, value string) {
+		if value != "" {
+			props = append(props, [2]string{label, value})
+		}
+	}
+	add("Materialized", doc.Materialized)
+	if doc.Relation != "" {
+		add("Relation", "`"+doc.Relation+"`")
+	}
+	add("Owner", doc.Owner)
+	add("Tags", strings.Join(doc.Tags, ", "))
+	for _, kv := range doc.Meta {
+		add("Meta `"+kv[0]+"`", kv[1])
+	}
+	add("Model tests", strings.Join(doc.ModelTests, ", "))
+	if len(props) > 0 {
+		sb.WriteString("| | |\n|---|---|\n")
+		for _, p := range props {
+			fmt.Fprintf(&sb, "| %s | %s |\n", p[0], markdownCell(p[1]))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(doc.Columns) > 0 {
+		sb.WriteString("## Columns\n\n| Column | Type | Tests | Description |\n|---|---|---|---|\n")
+		for _, c := range doc.Columns {
+			fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n",
+				c.Name, markdownCell(c.DataType), markdownCell(strings.Join(c.Tests, ", ")), markdownCell(c.Description))
+		}
+		sb.WriteString("\n")
+	}
+
+	list := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "## %s\n\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&sb, "- %s\n", item)
+		}
+		sb.WriteString("\n")
+	}
+	list("Upstream", doc.Upstream)
+	list("Downstream", doc.Downstream)
+
+	sb.WriteString("## Files\n\n")
+	for _, f := range doc.Files {
+		fmt.Fprintf(&sb, "- %s: `%s`\n", f[0], f[1])
+	}
+	return sb.String()
+}
+
+// markdownCell makes text safe to put in a Markdown table cell
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// wrapText breaks s into lines of at most width characters, keeping paragraphs
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func init() {
+	rootCmd.AddCommand(docCmd)
+
+	docCmd.Flags().BoolVar(&docMarkdown, "markdown", false, "Print Markdown instead of the terminal view")
+	registerModelCompletion(docCmd, false)
+}
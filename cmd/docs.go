@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	docsSelect []string
+	docsApply  bool
+)
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Manage model documentation",
+}
+
+var docsPropagateCmd = &cobra.Command{
+	Use:   "propagate",
+	Short: "Copy column descriptions from upstream models and sources",
+	Long: `Fill in missing column descriptions from upstream models and sources.
+
+A documented column with an empty description takes the description of the
+same column in a parent model or source, following simple renames
+(` + "`a.col as new_name`" + `) in the compiled SQL. Models are walked parents first,
+so descriptions flow down several levels at once. With --select only the
+selected models change, and descriptions only flow down through them.
+
+Prints a diff of the YAML changes; pass --apply to write them. Only the changed
+lines are touched, the rest of each file is left as it is.`,
+	Args: cobra.NoArgs,
+	RunE: docsPropagateRun,
+}
+
+func docsPropagateRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var only map[string]bool
+	if len(docsSelect) > 0 {
+		names, err := core.ListModels(cmd.Context(), docsSelect, dbtDir, isVerbose)
+		if err != nil {
+			return fmt.Errorf("error running dbt ls: %w", err)
+		}
+		only = map[string]bool{}
+		for _, name := range names {
+			if node, err := manifest.Model(name); err == nil {
+				only[node.UniqueID] = true
+			}
+		}
+	}
+
+	changes := core.PropagateDescriptions(manifest, dbtDir, only)
+
+	byFile := map[string][]core.DescriptionChange{}
+	for _, c := range changes {
+		path := c.Node.PatchFile(dbtDir)
+		if path == "" {
+			core.LogVerbose(isVerbose, "%s has no YAML file, skipping %s", c.Node.Name, c.Column)
+			continue
+		}
+		byFile[path] = append(byFile[path], c)
+	}
+	paths := make([]string, 0, len(byFile))
+	for p := range byFile {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	filled := 0
+	for _, path := range paths {
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		f, err := core.LoadSchemaFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+
+		for _, c := range byFile[path] {
+			col := f.Column(f.Model(c.Node.Name, false), c.Column, false)
+			if col == nil || !core.SetIfEmpty(col, "description", c.Description) {
+				continue
+			}
+			filled++
+
+			source := c.From.Name
+			if c.From.ResourceType == "source" {
+				source = c.From.SourceName + "." + c.From.Name
+			}
+			if c.FromColumn != c.Column {
+				source += "." + c.FromColumn
+			}
+			core.ColorPrint(core.Bold+core.Green, "+ ")
+			fmt.Printf("%s.%s %s← %s", c.Node.Name, c.Column, core.Dim, source)
+			if c.Candidates > 1 {
+				fmt.Printf(" (%d upstream candidates, took the first)", c.Candidates)
+			}
+			fmt.Println(core.Reset)
+		}
+
+		after, err := f.Bytes()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dbtDir, path)
+		if err != nil {
+			rel = path
+		}
+		fmt.Println()
+		fmt.Print(core.UnifiedDiff(rel, string(before), string(after)))
+		fmt.Println()
+
+		if docsApply {
+			if err := f.Save(); err != nil {
+				return fmt.Errorf("error writing %s: %w", path, err)
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("Descriptions Found: %s%d%s\nFiles: %d", core.Green, filled, core.Reset, len(paths))
+	switch {
+	case filled == 0:
+		summary = "Nothing to propagate"
+	case docsApply:
+		summary += "\nWritten"
+	default:
+		summary += "\nRun with --apply to write them"
+	}
+	core.PrintBox("Propagate Descriptions", summary, core.BoxRounded, core.BrightCyan)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsPropagateCmd)
+
+	docsPropagateCmd.Flags().StringSliceVarP(&docsSelect, "select", "s", []string{}, "Select models to fill in (default is every model)")
+	docsPropagateCmd.Flags().BoolVar(&docsApply, "apply", false, "Write the changes instead of previewing them")
+	registerModelCompletion(docsPropagateCmd, true)
+}
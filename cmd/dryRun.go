@@ -4,12 +4,14 @@ Copyright © 2025 Matthew Thornton
 package cmd
 
 import (
+	"context"
 	"dibbity/core"
 	"github.com/spf13/viper"
 
 	"fmt"
 	"github.com/spf13/cobra"
-	"log"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -17,15 +19,42 @@ var dryRunCmd = &cobra.Command{
 	Use:   "dryRun",
 	Short: "Dry run selected models",
 	Long:  "Dry run selected models",
-	Run:   dryRunRun,
+	RunE:  dryRunRun,
 }
 
 type Model struct {
-	Name      string
-	Path      string
-	SQL       string
-	CostBytes int
-	BQRunner  core.BqRunner
+	Name         string
+	Path         string
+	SQL          string
+	CostBytes    int
+	BQRunner     core.BqRunner
+	Materialized string
+	Modes        []ModelMode // with --both-modes, one dry run per incremental compile mode
+	Artefact     string      // which dbt artefact was analysed, see artefactCompiled / artefactRun
+}
+
+// artefacts that can be dry run: the compiled SELECT, or the full DDL/DML from `dbt run`
+const (
+	artefactCompiled = "compiled"
+	artefactRun      = "run"
+)
+
+// ModelMode is the dry run of one compile mode of an incremental model
+type ModelMode struct {
+	Mode     string // "full-refresh" or "incremental"
+	Path     string
+	BQRunner core.BqRunner
+}
+
+// compile modes for --both-modes, compiled into their own target paths so
+// they don't clobber target/ or each other
+var incrementalModes = []struct {
+	Mode        string
+	FullRefresh bool
+	TargetPath  string
+}{
+	{"full-refresh", true, filepath.Join("target", "dibbity", "full-refresh")},
+	{"incremental", false, filepath.Join("target", "dibbity", "incremental")},
 }
 
 var (
@@ -33,10 +62,14 @@ var (
 	shouldCompile    bool
 	shouldDefer      bool
 	shouldEmptyBuild bool
+	noCache          bool
+	bothModes        bool
+	useRunSQL        bool
+	shouldRecompile  bool
 )
 
 // TODO: I am sure I should refactor this and split out the functionality
-func dryRunRun(cmd *cobra.Command, args []string) {
+func dryRunRun(cmd *cobra.Command, args []string) error {
 	var err error
 
 	dbtOpts := core.DbtOptions{
@@ -44,23 +77,38 @@ func dryRunRun(cmd *cobra.Command, args []string) {
 		Empty:   shouldEmptyBuild,
 		Defer:   shouldDefer,
 		Compile: shouldCompile,
+
+		StatePath: viper.GetString("state-path"),
 	}
 
 	isVerbose := viper.GetBool("verbose")
 	dbtDir, err := core.GetFolder(isVerbose)
 	if err != nil {
-		log.Fatalf("Error getting dbt folder: %v", err)
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	budget, err := core.ParseBytes(viper.GetString("max-bytes"))
+	if err != nil {
+		return fmt.Errorf("--max-bytes: %w", err)
 	}
 
 	selectedModels = append(selectedModels, args...)
+	if len(selectedModels) == 0 {
+		selectedModels, err = pickModels(dbtDir, true, isVerbose)
+		if err != nil {
+			return err
+		}
+	}
+	dbtOpts.Select = selectedModels
+
 	// Print fancy header
 	fmt.Println()
 	// TODO: ensure that the length of models is using the expanded number
 	core.PrintBox(fmt.Sprintf("BigQuery Dry Run: %d models", len(selectedModels)), fmt.Sprintf("Models: %s", strings.Join(selectedModels, ", ")), core.BoxRounded, core.BrightCyan)
 	fmt.Println()
-	selectedModels, err = core.ListModels(selectedModels, dbtDir, isVerbose)
+	selectedModels, err = core.ListModels(cmd.Context(), selectedModels, dbtDir, isVerbose)
 	if err != nil {
-		log.Fatalf("error running dbt ls: %v", err)
+		return fmt.Errorf("error running dbt ls: %w", err)
 	}
 
 	core.LogVerbose(isVerbose, "Selected models: %v", selectedModels)
@@ -70,12 +118,12 @@ func dryRunRun(cmd *cobra.Command, args []string) {
 		core.ColorPrint(core.Bold+core.BrightBlue, "⚙️  ")
 		core.ColorPrintln(core.Bold+core.BrightBlue, "Compiling DBT models...")
 
-		err = core.CompileModel(dbtOpts, dbtDir, isVerbose)
+		err = core.CompileModel(cmd.Context(), dbtOpts, dbtDir, isVerbose)
 		if err != nil {
 			// Show error in a styled box if compilation fails
 			core.ColorPrintln(core.Bold+core.BrightRed, "❌ Compilation failed!")
 			core.PrintBox("Compilation Error", fmt.Sprintf("%v", err), core.BoxRounded, core.Red)
-			log.Fatalf("Error compiling models: %v", err)
+			return fmt.Errorf("error compiling models: %w", err)
 		} else {
 			// Show success message
 			core.ColorPrint(core.Bold+core.Green, "✓ ")
@@ -85,40 +133,107 @@ func dryRunRun(cmd *cobra.Command, args []string) {
 
 	}
 
+	artefact := artefactCompiled
+	if useRunSQL {
+		// target/run holds the statements dbt actually executed (CREATE OR REPLACE, MERGE, ...),
+		// only written by `dbt run` / `dbt build`, so --compile doesn't refresh it
+		artefact = artefactRun
+		if dbtOpts.Compile {
+			core.ColorPrintln(core.Yellow, "⚠ --compile does not update target/run, dry running the statements from the last `dbt run`")
+			fmt.Println()
+		}
+	}
+
+	// dbt ls has just parsed the project, so the manifest should be fresh
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		core.LogVerbose(isVerbose, "Could not load manifest, falling back to searching target/: %v", err)
+		manifest = nil
+	}
+
 	var models []Model
+	var stale []string
 
 	for _, modelName := range selectedModels {
-		fp, err := core.FindFilepath(modelName, dbtDir, "target", isVerbose)
+		fp, isStale, err := resolveModelSQL(manifest, modelName, dbtDir, artefact, isVerbose)
 		if err != nil {
-			log.Fatalf("Error finding model %s: %v", modelName, err)
+			if useRunSQL {
+				return fmt.Errorf("error finding run SQL for model %s (run `dbt run` or `dbt build --empty` first): %w", modelName, err)
+			}
+			return fmt.Errorf("error finding compiled SQL for model %s (try --compile): %w", modelName, err)
+		}
+		if isStale {
+			stale = append(stale, modelName)
+		}
+		models = append(models, Model{Name: modelName, Path: fp, Artefact: artefact})
+	}
+
+	if len(stale) > 0 {
+		core.ColorPrintln(core.Bold+core.Yellow, fmt.Sprintf("⚠ %s SQL is older than the source for: %s", artefact, strings.Join(stale, ", ")))
+		switch {
+		case artefact == artefactRun:
+			core.ColorPrintln(core.Yellow, "  re-run `dbt run` to refresh target/run")
+		case shouldRecompile || core.Confirm("Recompile stale models?", true):
+			staleOpts := dbtOpts
+			staleOpts.Select = stale
+			if err := core.CompileModel(cmd.Context(), staleOpts, dbtDir, isVerbose); err != nil {
+				core.ColorPrintln(core.Bold+core.BrightRed, "❌ Compilation failed!")
+				return fmt.Errorf("error recompiling stale models: %w", err)
+			}
+			core.ColorPrint(core.Bold+core.Green, "✓ ")
+			core.ColorPrintln(core.Bold+core.Green, "Recompiled stale models")
+		default:
+			core.ColorPrintln(core.Yellow, "  dry running stale SQL, pass --recompile or --compile to refresh it")
 		}
-		sql, err := core.LoadSQL(fp, isVerbose)
+		fmt.Println()
+	}
+
+	for i := range models {
+		sql, err := core.LoadSQL(models[i].Path, isVerbose)
 		if err != nil {
-			log.Fatalf("Error loading SQL for model %s: %v", modelName, err)
+			return fmt.Errorf("error loading SQL for model %s: %w", models[i].Name, err)
 		}
-		models = append(models, Model{Name: modelName, Path: fp, SQL: sql})
+		models[i].SQL = sql
 	}
 
 	core.LogVerbose(isVerbose, "Loaded %d models", len(models))
 
+	if bothModes {
+		if manifest == nil {
+			return fmt.Errorf("--both-modes needs target/manifest.json: %w", core.ErrNoManifest)
+		}
+		if err := compileIncrementalModes(cmd.Context(), manifest, models, dbtOpts, dbtDir, isVerbose); err != nil {
+			return err
+		}
+	}
+
+	var cache *core.DryRunCache
+	if !noCache {
+		cache, err = core.NewDryRunCache()
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(models) == 0 {
-		log.Fatalln("No models selected or found.")
+		return &core.NotFoundError{Kind: "model", Name: strings.Join(dbtOpts.Select, " ")}
 	}
 
 	for i := range models {
 		// Create a fancy model header
-		modelHeader := fmt.Sprintf("Model: %s", models[i].Name)
+		modelHeader := fmt.Sprintf("Model: %s [%s]", models[i].Name, models[i].Artefact)
 		core.ColorPrintln(core.Bold+core.BrightBlue, modelHeader)
 		core.ColorPrintln(core.Dim+core.BrightBlue, strings.Repeat("─", len(modelHeader)))
+		core.ColorPrintln(core.Dim, models[i].Path)
 
 		models[i].BQRunner = core.BqRunner{
 			Query: models[i].SQL,
 			Ok:    true,
 		}
 
-		_, err := models[i].BQRunner.BqDryRun(isVerbose)
+		_, err := models[i].BQRunner.BqDryRunCached(cmd.Context(), cache, isVerbose)
 		if err != nil {
-			log.Fatalf("Error running dry run: %v", err)
+			return fmt.Errorf("error running dry run: %w", err)
 		}
 
 		models[i].CostBytes = int(models[i].BQRunner.BytesProcessed)
@@ -130,37 +245,225 @@ func dryRunRun(cmd *cobra.Command, args []string) {
 		} else {
 			core.ColorPrint(core.Bold+core.Green, "✓ ")
 			core.ColorPrint(core.Bold, "Success - Data to process: ")
-			fmt.Println(FormatCost(models[i].CostBytes))
+			fmt.Print(FormatCost(models[i].CostBytes))
+			if models[i].BQRunner.Cached {
+				core.ColorPrint(core.Dim, " (cached)")
+			}
+			fmt.Println()
+		}
+		if n := len(models[i].BQRunner.Retries); n > 0 {
+			core.ColorPrintf(core.Yellow, "↻ Retried %d time(s): %s\n", n, strings.Join(models[i].BQRunner.Retries, ", "))
+		}
+
+		if len(models[i].Modes) > 0 {
+			if err := dryRunModes(cmd.Context(), &models[i], cache, isVerbose); err != nil {
+				return err
+			}
 		}
 		fmt.Println() // Add spacing between models
 	}
 
 	// Calculate and print summary
-	var totalCost int64
-	var successCount, failCount int
+	var totalCost, worstCost int64
+	var successCount, failCount, retryCount, cacheHits int
+	modeTotals := map[string]int64{}
+	var incrementalCount, modeFailCount int
 
 	for _, model := range models {
 		totalCost += int64(model.CostBytes)
+		retryCount += len(model.BQRunner.Retries)
+		if model.BQRunner.Cached {
+			cacheHits++
+		}
 		if model.BQRunner.Ok {
 			successCount++
 		} else {
 			failCount++
 		}
+
+		if len(model.Modes) > 0 {
+			incrementalCount++
+		}
+		// the budget is checked against the most expensive way each model can run
+		worst := int64(model.CostBytes)
+		for _, mode := range model.Modes {
+			modeTotals[mode.Mode] += mode.BQRunner.BytesProcessed
+			worst = max(worst, mode.BQRunner.BytesProcessed)
+			if !mode.BQRunner.Ok {
+				modeFailCount++
+			}
+		}
+		worstCost += worst
 	}
 
 	// Print summary in a fancy box
 	summary := fmt.Sprintf(
 		"Models Processed: %d\n"+
+			"Analysed: %s\n"+
 			"Successful: %s%d%s\n"+
 			"Failed: %s%d%s\n"+
+			"Retried Attempts: %s%d%s\n"+
+			"Cache Hits: %d\n"+
 			"Total Data to Process: %s",
 		len(models),
+		artefactDescription(artefact),
 		core.Green, successCount, core.Reset,
 		core.Red, failCount, core.Reset,
+		core.Yellow, retryCount, core.Reset,
+		cacheHits,
 		core.FormatBytes(totalCost),
 	)
 
+	if price := viper.GetFloat64("price-per-tib"); price > 0 {
+		summary += fmt.Sprintf("\nEstimated Cost: %s", core.FormatPrice(totalCost, price))
+	}
+
+	if incrementalCount > 0 {
+		summary += fmt.Sprintf("\nIncremental Models: %d", incrementalCount)
+		for _, m := range incrementalModes {
+			summary += fmt.Sprintf("\n  %s: %s", m.Mode, core.FormatBytes(modeTotals[m.Mode]))
+		}
+		summary += fmt.Sprintf("\n  worst case: %s", core.FormatBytes(worstCost))
+	}
+
 	core.PrintBox("Dry Run Summary", summary, core.BoxDouble, core.BrightMagenta)
+
+	if failCount > 0 {
+		return &core.BqError{Msg: fmt.Sprintf("%d of %d models failed to dry run", failCount, len(models))}
+	}
+
+	if modeFailCount > 0 {
+		return &core.BqError{Msg: fmt.Sprintf("%d incremental mode dry runs failed", modeFailCount)}
+	}
+
+	if budget > 0 && worstCost > budget {
+		return &core.BudgetExceededError{Limit: budget, Actual: worstCost}
+	}
+
+	return nil
+}
+
+// resolveModelSQL finds the SQL to dry run for a model. With a manifest the path
+// comes from the model's compiled_path / original_file_path, so we never pick up
+// a stale package copy or the wrong artefact; without one we fall back to
+// searching the artefact directory. It also reports whether the SQL is older
+// than the model source.
+func resolveModelSQL(manifest *core.Manifest, modelName string, dbtDir string, artefact string, b bool) (string, bool, error) {
+	if manifest == nil {
+		fp, err := core.FindFilepath(modelName, dbtDir, filepath.Join("target", artefact), b)
+		return fp, false, err
+	}
+
+	node, err := manifest.Model(modelName)
+	if err != nil {
+		return "", false, err
+	}
+
+	fp := node.ArtefactPath(dbtDir, artefact)
+	if _, err := os.Stat(fp); err != nil {
+		return "", false, &core.NotFoundError{Kind: artefact + " SQL", Name: fp}
+	}
+	core.LogVerbose(b, "Resolved %s SQL for %s to %s", artefact, modelName, fp)
+
+	isStale, err := core.IsStale(fp, node.SourcePath(dbtDir))
+	if err != nil {
+		core.LogVerbose(b, "Could not check %s is up to date: %v", fp, err)
+	}
+	return fp, isStale, nil
+}
+
+// artefactDescription labels which SQL was dry run in the summary
+func artefactDescription(artefact string) string {
+	if artefact == artefactRun {
+		return "target/run (DDL/DML dbt executes)"
+	}
+	return "target/compiled (SELECT only)"
+}
+
+// compileIncrementalModes finds the incremental models in the manifest and
+// compiles them twice, once with --full-refresh and once without, so that
+// both sides of is_incremental() can be dry run
+func compileIncrementalModes(ctx context.Context, manifest *core.Manifest, models []Model, opts core.DbtOptions, dbtDir string, b bool) error {
+	var incrementals []string
+	nodes := map[string]*core.Node{}
+	for i := range models {
+		node, err := manifest.Model(models[i].Name)
+		if err != nil {
+			core.LogVerbose(b, "Model %s not in manifest, skipping --both-modes", models[i].Name)
+			continue
+		}
+		models[i].Materialized = node.Config.Materialized
+		if node.IsIncremental() {
+			incrementals = append(incrementals, node.Name)
+			nodes[node.Name] = node
+		}
+	}
+
+	if len(incrementals) == 0 {
+		core.ColorPrintln(core.Dim, "No incremental models selected, --both-modes has nothing to do.")
+		fmt.Println()
+		return nil
+	}
+
+	for _, mode := range incrementalModes {
+		core.ColorPrint(core.Bold+core.BrightBlue, "⚙️  ")
+		core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("Compiling %d incremental models in %s mode...", len(incrementals), mode.Mode))
+
+		modeOpts := opts
+		modeOpts.Select = incrementals
+		modeOpts.FullRefresh = mode.FullRefresh
+		modeOpts.TargetPath = mode.TargetPath
+		if err := core.CompileModel(ctx, modeOpts, dbtDir, b); err != nil {
+			core.ColorPrintln(core.Bold+core.BrightRed, "❌ Compilation failed!")
+			return fmt.Errorf("error compiling %s mode: %w", mode.Mode, err)
+		}
+	}
+	fmt.Println()
+
+	for i := range models {
+		node, ok := nodes[models[i].Name]
+		if !ok {
+			continue
+		}
+		for _, mode := range incrementalModes {
+			fp := filepath.Join(dbtDir, node.CompiledPathIn(mode.TargetPath))
+			sql, err := core.LoadSQL(fp, b)
+			if err != nil {
+				return fmt.Errorf("error loading %s SQL for model %s: %w", mode.Mode, node.Name, err)
+			}
+			models[i].Modes = append(models[i].Modes, ModelMode{
+				Mode:     mode.Mode,
+				Path:     fp,
+				BQRunner: core.BqRunner{Query: sql, Ok: true},
+			})
+		}
+	}
+	return nil
+}
+
+// dryRunModes dry runs each compile mode of an incremental model and prints the costs side by side
+func dryRunModes(ctx context.Context, m *Model, cache *core.DryRunCache, b bool) error {
+	var lines []string
+	for i := range m.Modes {
+		mode := &m.Modes[i]
+		if _, err := mode.BQRunner.BqDryRunCached(ctx, cache, b); err != nil {
+			return fmt.Errorf("error running %s dry run: %w", mode.Mode, err)
+		}
+
+		result := FormatCost(int(mode.BQRunner.BytesProcessed))
+		if !mode.BQRunner.Ok {
+			result = core.Red + "failed" + core.Reset
+		}
+		lines = append(lines, fmt.Sprintf("%-13s %s", mode.Mode+":", result))
+	}
+
+	core.PrintBox("Incremental Modes", strings.Join(lines, "\n"), core.BoxRounded, core.Cyan)
+	for _, mode := range m.Modes {
+		if !mode.BQRunner.Ok {
+			core.PrintBox(fmt.Sprintf("Error (%s)", mode.Mode), mode.BQRunner.RespError, core.BoxRounded, core.Red)
+		}
+	}
+	return nil
 }
 
 // FormatCost calculates the total cost in bytes of all models
@@ -172,14 +475,18 @@ func FormatCost(bytes int) string {
 func init() {
 	rootCmd.AddCommand(dryRunCmd)
 
-	dryRunCmd.Flags().StringSliceVarP(&selectedModels, "select", "s", []string{}, "Select models to run")
-	if err := dryRunCmd.MarkFlagRequired("select"); err != nil {
-		log.Fatalf("Error: could not mark --select flag as required: %v", err)
-	}
+	dryRunCmd.Flags().StringSliceVarP(&selectedModels, "select", "s", []string{}, "Select models to run (opens a picker if omitted)")
+	registerModelCompletion(dryRunCmd, true)
 
 	dryRunCmd.Flags().BoolVarP(&shouldCompile, "compile", "c", false, "Compile new model")
 	dryRunCmd.Flags().BoolVarP(&shouldDefer, "defer", "d", false, "Use deferred build")
 	dryRunCmd.Flags().BoolVarP(&shouldEmptyBuild, "empty", "e", false, "Use empty build")
+	dryRunCmd.Flags().BoolVar(&shouldRecompile, "recompile", false, "Recompile models whose compiled SQL is older than the source without asking")
+	dryRunCmd.Flags().BoolVar(&useRunSQL, "run-sql", false, "Dry run the DDL/DML from target/run instead of the compiled SELECT")
+	dryRunCmd.Flags().BoolVar(&bothModes, "both-modes", false, "Dry run incremental models in both full-refresh and incremental modes")
+	dryRunCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	dryRunCmd.Flags().String("max-bytes", "", "Fail if total data to process exceeds this size (e.g. 500MB, 2TB); with --both-modes, the costlier mode of each model counts")
+	cobra.CheckErr(viper.BindPFlag("max-bytes", dryRunCmd.Flags().Lookup("max-bytes")))
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,199 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	historyYAML  bool
+	historyLimit int
+	historyDiff  bool
+	historyBlame bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [model]",
+	Short: "Show the git history of a model",
+	Long: `Show the commits that changed a model's SQL file, following renames, with
+--yaml adding the schema.yml that documents it.
+
+--diff shows the changes each commit made, and --blame shows who last changed
+each line, with the most recent commit highlighted.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: historyRun,
+}
+
+func historyRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	files, err := modelFiles(name, dbtDir, historyYAML, isVerbose)
+	if err != nil {
+		return err
+	}
+	core.LogVerbose(isVerbose, "History of %s from %v", name, files)
+
+	switch {
+	case historyBlame:
+		for _, f := range files {
+			lines, err := core.GitBlame(cmd.Context(), dbtDir, f)
+			if err != nil {
+				return err
+			}
+			printBlame(f, lines)
+		}
+	case historyDiff:
+		for _, f := range files {
+			out, err := core.GitLogPatch(cmd.Context(), dbtDir, f, historyLimit)
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			core.ColorPrintln(core.Bold+core.BrightBlue, f)
+			fmt.Print(out)
+		}
+	default:
+		commits, err := core.GitLog(cmd.Context(), dbtDir, files, historyLimit)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("History of %s", name))
+		core.ColorPrintln(core.Dim, strings.Join(files, ", "))
+		fmt.Println()
+		if len(commits) == 0 {
+			core.ColorPrintln(core.Dim, "  no commits yet")
+			return nil
+		}
+		var rows [][]string
+		for _, c := range commits {
+			subject := c.Subject
+			if len(files) > 1 {
+				subject += core.Dim + " (" + strings.Join(fileKinds(c.Files, files[0]), ", ") + ")" + core.Reset
+			}
+			rows = append(rows, []string{core.Yellow + c.Hash[:min(len(c.Hash), 8)] + core.Reset, c.Time.Format("2006-01-02"), c.Author, subject})
+		}
+		core.PrintTable([]string{"COMMIT", "DATE", "AUTHOR", "SUBJECT"}, rows, 72)
+	}
+	return nil
+}
+
+// modelFiles returns a model's SQL file, and optionally its YAML, relative to dbtDir
+func modelFiles(name string, dbtDir string, withYAML bool, b bool) ([]string, error) {
+	manifest, err := core.LoadManifest(dbtDir, b)
+	if err != nil {
+		core.LogVerbose(b, "Could not load manifest, falling back to searching models/: %v", err)
+		if withYAML {
+			return nil, fmt.Errorf("--yaml needs target/manifest.json: %w", core.ErrNoManifest)
+		}
+		path, err := core.FindFilepath(name, dbtDir, "models", b)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(dbtDir, path)
+		if err != nil {
+			return nil, err
+		}
+		return []string{rel}, nil
+	}
+
+	node, err := manifest.Model(name)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{node.OriginalFilePath}
+	if withYAML {
+		yml := node.PatchFile(dbtDir)
+		if yml == "" {
+			core.ColorPrintln(core.Yellow, fmt.Sprintf("⚠ %s is not documented in any YAML file", name))
+		} else if rel, err := filepath.Rel(dbtDir, yml); err == nil {
+			files = append(files, rel)
+		}
+	}
+	return files, nil
+}
+
+// fileKinds labels the files a commit touched as "sql" or "yaml"
+func fileKinds(touched []string, sqlFile string) []string {
+	var kinds []string
+	for _, f := range touched {
+		if f == sqlFile {
+			kinds = append(kinds, "sql")
+		} else {
+			kinds = append(kinds, "yaml")
+		}
+	}
+	return kinds
+}
+
+// blameColors tell authors apart in --blame
+var blameColors = []string{core.Cyan, core.Magenta, core.Blue, core.Green, core.Yellow, core.BrightCyan, core.BrightMagenta}
+
+func printBlame(path string, lines []core.BlameLine) {
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, path)
+
+	latest, authorWidth := "", 0
+	var latestTime int64
+	colors := map[string]string{}
+	for _, l := range lines {
+		if t := l.Time.Unix(); t > latestTime {
+			latest, latestTime = l.Hash, t
+		}
+		if _, ok := colors[l.Author]; !ok {
+			colors[l.Author] = blameColors[len(colors)%len(blameColors)]
+		}
+		authorWidth = max(authorWidth, len(l.Author))
+	}
+
+	for _, l := range lines {
+		text := l.Text
+		if l.Hash == latest {
+			text = core.Bold + text + core.Reset
+		}
+		fmt.Printf("%s%s%s %s%-*s%s %s %s%4d%s │ %s\n",
+			core.Yellow, l.Hash[:8], core.Reset,
+			colors[l.Author], authorWidth, l.Author, core.Reset,
+			l.Time.Format("2006-01-02"),
+			core.Dim, l.Line, core.Reset,
+			text)
+	}
+	if latest != "" {
+		fmt.Println()
+		core.ColorPrintln(core.Dim, fmt.Sprintf("bold lines were last changed in %s", latest[:8]))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().BoolVar(&historyYAML, "yaml", false, "Include the schema.yml that documents the model")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of commits to show, 0 for all")
+	historyCmd.Flags().BoolVar(&historyDiff, "diff", false, "Show the changes made by each commit")
+	historyCmd.Flags().BoolVar(&historyBlame, "blame", false, "Show who last changed each line")
+	historyCmd.MarkFlagsMutuallyExclusive("diff", "blame")
+	registerModelCompletion(historyCmd, false)
+}
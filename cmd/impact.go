@@ -0,0 +1,176 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	impactTop     int
+	impactCompile bool
+	impactNoCache bool
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact [model]",
+	Short: "Estimate the cost of rebuilding everything downstream of a model",
+	Long: `Estimate what it costs to rebuild a model and everything downstream of it
+(dbt's model+), using target/manifest.json.
+
+Each table and incremental model is dry run; views and ephemeral models are
+skipped as rebuilding them scans no data. Prints the total bytes, the estimated
+cost when price-per-tib is set, and the most expensive models.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: impactRun,
+}
+
+func impactRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	if impactCompile {
+		opts := core.DbtOptions{Select: []string{name + "+"}, Compile: true, StatePath: viper.GetString("state-path")}
+		if err := core.CompileModel(cmd.Context(), opts, dbtDir, isVerbose); err != nil {
+			return fmt.Errorf("error compiling %s+: %w", name, err)
+		}
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+
+	var cache *core.DryRunCache
+	if !impactNoCache {
+		if cache, err = core.NewDryRunCache(); err != nil {
+			return err
+		}
+	}
+
+	graph := manifest.Lineage([]string{node.UniqueID}, 0, -1)
+	depths := map[string]int{}
+	var models []Model
+	var skipped []string
+	for _, ln := range graph.Nodes {
+		if ln.Node.ResourceType != "model" {
+			continue
+		}
+		switch ln.Node.Config.Materialized {
+		case "view", "ephemeral":
+			skipped = append(skipped, ln.Node.Name)
+			continue
+		}
+		depths[ln.Node.Name] = ln.Depth
+		models = append(models, Model{Name: ln.Node.Name, Materialized: ln.Node.Config.Materialized, Artefact: artefactCompiled})
+	}
+
+	fmt.Println()
+	core.PrintBox(fmt.Sprintf("Impact of %s", name),
+		fmt.Sprintf("Downstream models: %d\nTo dry run: %d\nSkipped (views, ephemeral): %d", len(models)+len(skipped)-1, len(models), len(skipped)),
+		core.BoxRounded, core.BrightCyan)
+	fmt.Println()
+
+	var failed []string
+	var totalCost int64
+	for i := range models {
+		runner, err := dryRunModel(cmd.Context(), manifest, models[i].Name, dbtDir, cache, isVerbose)
+		if err != nil {
+			core.ColorPrint(core.Bold+core.Red, "✗ ")
+			fmt.Printf("%s: %v\n", models[i].Name, err)
+			failed = append(failed, models[i].Name)
+			continue
+		}
+		models[i].BQRunner = *runner
+		models[i].CostBytes = int(runner.BytesProcessed)
+		totalCost += runner.BytesProcessed
+		core.LogVerbose(isVerbose, "%s: %s", models[i].Name, FormatCost(models[i].CostBytes))
+	}
+
+	sort.SliceStable(models, func(i, j int) bool { return models[i].CostBytes > models[j].CostBytes })
+
+	price := viper.GetFloat64("price-per-tib")
+	headers := []string{"MODEL", "MATERIALIZED", "DEPTH", "DATA TO PROCESS"}
+	if price > 0 {
+		headers = append(headers, "COST")
+	}
+	var rows [][]string
+	for _, m := range models {
+		if len(rows) == impactTop {
+			break
+		}
+		if !m.BQRunner.Ok {
+			continue
+		}
+		row := []string{m.Name, m.Materialized, strconv.Itoa(depths[m.Name]), FormatCost(m.CostBytes)}
+		if price > 0 {
+			row = append(row, core.FormatPrice(int64(m.CostBytes), price))
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) > 0 {
+		if len(failed) > 0 {
+			fmt.Println()
+		}
+		core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("Most expensive models (top %d)", len(rows)))
+		core.PrintTable(headers, rows, 60)
+		fmt.Println()
+	}
+
+	summary := fmt.Sprintf(
+		"Models Dry Run: %d\n"+
+			"Failed: %s%d%s\n"+
+			"Total Data to Process: %s",
+		len(models)-len(failed),
+		core.Red, len(failed), core.Reset,
+		core.FormatBytes(totalCost),
+	)
+	if price > 0 {
+		summary += fmt.Sprintf("\nEstimated Cost: %s", core.FormatPrice(totalCost, price))
+	}
+	if len(skipped) > 0 {
+		summary += fmt.Sprintf("\nSkipped: %s", strings.Join(skipped, ", "))
+	}
+	core.PrintBox("Impact Summary", summary, core.BoxDouble, core.BrightMagenta)
+
+	if len(failed) > 0 {
+		return &core.BqError{Msg: fmt.Sprintf("%d of %d models failed to dry run: %s", len(failed), len(models), strings.Join(failed, ", "))}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+
+	impactCmd.Flags().IntVarP(&impactTop, "top", "n", 10, "Number of most expensive models to list")
+	impactCmd.Flags().BoolVarP(&impactCompile, "compile", "c", false, "Compile the model and its descendants first")
+	impactCmd.Flags().BoolVar(&impactNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	registerModelCompletion(impactCmd, false)
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"bytes"
+	"dibbity/core"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	initOutput string
+	initForce  bool
+	initLocal  bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [dbt-dir]",
+	Short: "Create a .dibbity.yaml for a dbt project",
+	Long: `Create a commented .dibbity.yaml.
+
+Looks for dbt_project.yml in the given directory (or walks up from the
+current one), detects the python runner from the lock files, reads the
+BigQuery project and location from profiles.yml and checks that bq and
+gcloud credentials are available.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: initRun,
+}
+
+// initCheck is one line of the init / validate report
+type initCheck struct {
+	ok     bool
+	name   string
+	detail string
+}
+
+func printChecks(checks []initCheck) {
+	for _, c := range checks {
+		if c.ok {
+			core.ColorPrint(core.Bold+core.Green, "✓ ")
+		} else {
+			core.ColorPrint(core.Bold+core.Yellow, "⚠ ")
+		}
+		core.ColorPrint(core.Bold, c.name+": ")
+		fmt.Println(c.detail)
+	}
+}
+
+func initRun(cmd *cobra.Command, args []string) error {
+	start := "."
+	if len(args) == 1 {
+		start = args[0]
+	}
+
+	dbtDir, ok := core.FindProjectRoot(start)
+	if !ok {
+		return &core.NotFoundError{Kind: "dbt project (dbt_project.yml)", Name: start}
+	}
+
+	var checks []initCheck
+	values := map[string]string{"dbt-dir": dbtDir}
+	checks = append(checks, initCheck{true, "dbt project", dbtDir})
+
+	runner := core.DetectRunner(dbtDir)
+	values["runner"] = runner
+	if prefix := core.Runners[runner]; len(prefix) > 0 {
+		_, err := exec.LookPath(prefix[0])
+		checks = append(checks, initCheck{err == nil, "runner", runnerDetail(runner, err)})
+	} else {
+		checks = append(checks, initCheck{true, "runner", "none (dbt on $PATH)"})
+	}
+
+	project, err := core.LoadDbtProject(dbtDir)
+	if err != nil {
+		checks = append(checks, initCheck{false, "dbt_project.yml", err.Error()})
+	} else if target, err := core.LoadProfileTarget(dbtDir, project.Profile); err != nil {
+		checks = append(checks, initCheck{false, "profile", fmt.Sprintf("%s: %v", project.Profile, err)})
+	} else {
+		values["bq-project"] = target.Project
+		if target.Location != "" {
+			values["bq-location"] = target.Location
+		}
+		checks = append(checks, initCheck{target.Project != "", "profile",
+			fmt.Sprintf("%s.%s → project %q location %q (%s)", project.Profile, target.Name, target.Project, target.Location, target.Path)})
+	}
+
+	checks = append(checks, toolCheck("bq"))
+	if creds := core.HasGcloudCredentials(); creds != "" {
+		checks = append(checks, initCheck{true, "credentials", creds})
+	} else {
+		checks = append(checks, initCheck{false, "credentials", "none found, run `gcloud auth application-default login`"})
+	}
+
+	printChecks(checks)
+	fmt.Println()
+
+	out := initOutput
+	if initLocal {
+		out = filepath.Join(dbtDir, ".dibbity.yaml")
+	}
+	if out == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &core.ConfigError{Msg: "failed to get user home directory", Err: err}
+		}
+		out = filepath.Join(home, ".dibbity.yaml")
+	}
+
+	if _, err := os.Stat(out); err == nil && !initForce {
+		return &core.ConfigError{Msg: fmt.Sprintf("%s already exists (use --force to overwrite)", out)}
+	}
+
+	var buf bytes.Buffer
+	header := fmt.Sprintf("dibbity configuration, generated by `dibbity init` for %s\n"+
+		"Every key can also be set with a flag or environment variable, see `dibbity config show`.", dbtDir)
+	if err := core.WriteConfigTemplate(&buf, values, header); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+
+	core.ColorPrint(core.Bold+core.Green, "✓ ")
+	fmt.Printf("Wrote %s\n", out)
+	core.ColorPrintln(core.Dim, "  run `dibbity config validate` to check it")
+	return nil
+}
+
+func runnerDetail(runner string, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%s (not found on $PATH)", runner)
+	}
+	return runner
+}
+
+func toolCheck(name string) initCheck {
+	p, err := exec.LookPath(name)
+	if err != nil {
+		return initCheck{false, name, "not found on $PATH"}
+	}
+	return initCheck{true, name, p}
+}
+
+// validateConfig checks the effective configuration and returns one check per problem area
+func validateConfig() []initCheck {
+	var checks []initCheck
+
+	var notFound viper.ConfigFileNotFoundError
+	switch {
+	case configErr != nil && !errors.As(configErr, &notFound):
+		checks = append(checks, initCheck{false, "config file", configErr.Error()})
+	case configErr != nil:
+		checks = append(checks, initCheck{false, "config file", "none found, run `dibbity init`"})
+	}
+	for _, layer := range configLayers {
+		if !layer.file {
+			continue
+		}
+		checks = append(checks, initCheck{true, "config file", layer.path})
+		for _, key := range layer.v.AllKeys() {
+			if !core.IsConfigKey(key) {
+				checks = append(checks, initCheck{false, "unknown key", fmt.Sprintf("%s (%s)", key, layer.path)})
+			}
+		}
+	}
+
+	if projectErr != nil {
+		checks = append(checks, initCheck{false, "project", projectErr.Error()})
+	} else if p := core.ActiveProject(); p != "" {
+		checks = append(checks, initCheck{true, "project", p})
+	}
+
+	if dbtDir, err := core.GetFolder(false); err != nil {
+		checks = append(checks, initCheck{false, "dbt-dir", err.Error()})
+	} else if _, err := os.Stat(filepath.Join(dbtDir, "dbt_project.yml")); err != nil {
+		checks = append(checks, initCheck{false, "dbt-dir", dbtDir + " has no dbt_project.yml"})
+	} else {
+		checks = append(checks, initCheck{true, "dbt-dir", dbtDir})
+	}
+
+	runner := viper.GetString("runner")
+	if runner == "" {
+		runner = "poetry"
+	}
+	if prefix, ok := core.Runners[runner]; !ok {
+		checks = append(checks, initCheck{false, "runner", fmt.Sprintf("unknown runner %q, expected poetry, uv, pipenv or none", runner)})
+	} else if len(prefix) > 0 {
+		_, err := exec.LookPath(prefix[0])
+		checks = append(checks, initCheck{err == nil, "runner", runnerDetail(runner, err)})
+	}
+
+	for _, key := range []string{"timeout", "dbt-timeout", "bq-timeout", "bq-retry-backoff", "bq-retry-max-backoff", "cache-ttl"} {
+		if !viper.IsSet(key) {
+			continue
+		}
+		if _, err := cast.ToDurationE(viper.Get(key)); err != nil {
+			checks = append(checks, initCheck{false, key, fmt.Sprintf("%q is not a duration (e.g. 30s, 5m, 1h)", viper.GetString(key))})
+		}
+	}
+
+	checks = append(checks, toolCheck("bq"))
+	return checks
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVarP(&initOutput, "output", "o", "", "Where to write the config (default is $HOME/.dibbity.yaml)")
+	initCmd.Flags().BoolVar(&initLocal, "local", false, "Write a project-local .dibbity.yaml next to dbt_project.yml")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing config file")
+}
@@ -0,0 +1,213 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"context"
+	"dibbity/core"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	lineageUp      int
+	lineageDown    int
+	lineageFormat  string
+	lineageBytes   bool
+	lineageNoCache bool
+)
+
+var lineageFormats = []string{"ascii", "dot", "mermaid", "json"}
+
+var lineageCmd = &cobra.Command{
+	Use:   "lineage <selector>",
+	Short: "Export the DAG around models as DOT, Mermaid, JSON or text",
+	Long: `Export the lineage of the selected models from target/manifest.json.
+
+The selector is a model name, optionally with dbt graph operators
+(+model, 2+model+1), or any dbt selector such as tag:nightly or path:marts/,
+which is resolved with dbt ls. --up and --down set how many levels of parents
+and children to include (-1 for all) and override any graph operators. As in
+dbt, +model shows only parents and model+ only children; with no operators or
+flags, one level each way is shown.
+
+Nodes are coloured by materialization. --bytes dry runs every table, view and
+incremental model in the graph and adds the bytes processed to each node.
+
+  dibbity lineage +fct_orders --format mermaid   # paste into a PR description
+  dibbity lineage stg_orders --down -1 --format dot | dot -Tsvg > lineage.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: lineageRun,
+}
+
+func lineageRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	format := strings.ToLower(lineageFormat)
+	if !slices.Contains(lineageFormats, format) {
+		return &core.ConfigError{Msg: fmt.Sprintf("unknown --format %q, expected one of %s", lineageFormat, strings.Join(lineageFormats, ", "))}
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	up, down, selector := parseGraphOperators(args[0])
+	if cmd.Flags().Changed("up") {
+		up = lineageUp
+	}
+	if cmd.Flags().Changed("down") {
+		down = lineageDown
+	}
+
+	nodes, err := selectNodes(cmd.Context(), manifest, selector, dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.UniqueID)
+	}
+	core.LogVerbose(isVerbose, "Lineage of %v, %d up and %d down", ids, up, down)
+
+	graph := manifest.Lineage(ids, up, down)
+
+	if lineageBytes {
+		var cache *core.DryRunCache
+		if !lineageNoCache {
+			if cache, err = core.NewDryRunCache(); err != nil {
+				return err
+			}
+		}
+		for _, ln := range graph.Nodes {
+			if ln.Node.ResourceType != "model" || ln.Node.Config.Materialized == "ephemeral" {
+				continue
+			}
+			runner, err := dryRunModel(cmd.Context(), manifest, ln.Node.Name, dbtDir, cache, isVerbose)
+			if err != nil {
+				// stderr, so the graph can still be piped into dot or a file
+				fmt.Fprintln(os.Stderr, core.Yellow+fmt.Sprintf("⚠ skipping bytes for %s: %v", ln.Node.Name, err)+core.Reset)
+				continue
+			}
+			ln.Bytes = runner.BytesProcessed
+		}
+	}
+
+	switch format {
+	case "dot":
+		fmt.Print(graph.DOT())
+	case "mermaid":
+		fmt.Print(graph.Mermaid())
+	case "json":
+		out, err := graph.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+	default:
+		fmt.Println()
+		fmt.Print(graph.ASCII())
+		fmt.Println()
+		fmt.Printf("%d nodes, %d edges\n", len(graph.Nodes), len(graph.Edges))
+	}
+	return nil
+}
+
+// parseGraphOperators strips dbt's "N+" and "+N" graph operators from a
+// selector. A bare "+" means every level (-1). As in dbt, once either side has
+// an operator the other side gets none (0); with no operators at all one level
+// each way is shown.
+func parseGraphOperators(selector string) (int, int, string) {
+	up, down := 1, 1
+
+	var upOp, downOp bool
+	if i := strings.Index(selector, "+"); i >= 0 && isDigits(selector[:i]) {
+		upOp = true
+		up = -1
+		if i > 0 {
+			up, _ = strconv.Atoi(selector[:i])
+		}
+		selector = selector[i+1:]
+	}
+	if i := strings.LastIndex(selector, "+"); i >= 0 && isDigits(selector[i+1:]) {
+		downOp = true
+		down = -1
+		if i+1 < len(selector) {
+			down, _ = strconv.Atoi(selector[i+1:])
+		}
+		selector = selector[:i]
+	}
+	switch {
+	case upOp && !downOp:
+		down = 0
+	case downOp && !upOp:
+		up = 0
+	}
+	return up, down, selector
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// selectNodes resolves a selector to models, looking a plain model name up in
+// the manifest and anything else with dbt ls
+func selectNodes(ctx context.Context, manifest *core.Manifest, selector string, dbtDir string, b bool) ([]*core.Node, error) {
+	if isPlainModelName(selector) {
+		node, err := manifest.Model(selector)
+		if err != nil {
+			return nil, err
+		}
+		return []*core.Node{node}, nil
+	}
+
+	names, err := core.ListModels(ctx, []string{selector}, dbtDir, b)
+	if err != nil {
+		return nil, fmt.Errorf("error running dbt ls: %w", err)
+	}
+	var nodes []*core.Node
+	for _, name := range names {
+		if node, err := manifest.Model(name); err == nil {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, &core.NotFoundError{Kind: "models matching", Name: selector}
+	}
+	return nodes, nil
+}
+
+// isPlainModelName reports whether a selector names a single model, so we can
+// skip dbt ls
+func isPlainModelName(selector string) bool {
+	return selector != "" && !strings.ContainsAny(selector, ":,@*/ ")
+}
+
+func init() {
+	rootCmd.AddCommand(lineageCmd)
+
+	lineageCmd.Flags().IntVar(&lineageUp, "up", 1, "Levels of parents to include, -1 for all")
+	lineageCmd.Flags().IntVar(&lineageDown, "down", 1, "Levels of children to include, -1 for all")
+	lineageCmd.Flags().StringVarP(&lineageFormat, "format", "f", "ascii", "Output format: "+strings.Join(lineageFormats, ", "))
+	lineageCmd.Flags().BoolVar(&lineageBytes, "bytes", false, "Dry run models in the graph and annotate them with bytes processed")
+	lineageCmd.Flags().BoolVar(&lineageNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	_ = lineageCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(lineageFormats, cobra.ShellCompDirectiveNoFileComp))
+	registerModelCompletion(lineageCmd, true)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import "testing"
+
+func TestParseGraphOperators(t *testing.T) {
+	tests := []struct {
+		selector string
+		up, down int
+		name     string
+	}{
+		{"orders", 1, 1, "orders"},
+		{"+orders", -1, 0, "orders"},
+		{"orders+", 0, -1, "orders"},
+		{"+orders+", -1, -1, "orders"},
+		{"2+orders", 2, 0, "orders"},
+		{"orders+3", 0, 3, "orders"},
+		{"2+orders+0", 2, 0, "orders"},
+		{"tag:nightly", 1, 1, "tag:nightly"},
+		{"+path:models/marts", -1, 0, "path:models/marts"},
+		{"a+b", 1, 1, "a+b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			up, down, name := parseGraphOperators(tt.selector)
+			if up != tt.up || down != tt.down || name != tt.name {
+				t.Fatalf("parseGraphOperators(%q) = %d, %d, %q, want %d, %d, %q", tt.selector, up, down, name, tt.up, tt.down, tt.name)
+			}
+		})
+	}
+}
+
+func TestIsPlainModelName(t *testing.T) {
+	for s, want := range map[string]bool{
+		"orders":           true,
+		"stg_orders_v2":    true,
+		"tag:nightly":      false,
+		"models/marts":     false,
+		"orders,customers": false,
+		"@orders":          false,
+		"fct_*":            false,
+	} {
+		if got := isPlainModelName(s); got != want {
+			t.Errorf("isPlainModelName(%q) = %t, want %t", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	lookmlDryRun  bool
+	lookmlCompile bool
+	lookmlNoCache bool
+	lookmlFiles   bool
+)
+
+var lookmlCmd = &cobra.Command{
+	Use:   "lookml",
+	Short: "Compare dbt models with the LookML views built on them",
+	Long: `Commands that read the LookML project set by lookml-dir (or --lookml-dir)
+and match its views to dbt models by the tables their sql_table_name or derived
+table SQL select from.`,
+}
+
+var lookmlDiffCmd = &cobra.Command{
+	Use:   "diff [model]",
+	Short: "Compare a LookML view's fields with a model's columns",
+	Long: `Find the view whose sql_table_name is the model's table and compare the
+columns its dimensions and measures select (${TABLE}.column) with the model's
+columns, reporting:
+
+  - columns LookML references that the model no longer has
+  - model columns no LookML field exposes
+
+The model's columns come from its schema.yml, or from a dry run of the compiled
+SQL with --dry-run or when none are documented. schema.yml may not list every
+column, so only a reference missing from the dry run schema fails with exit
+code 8; one missing from schema.yml is reported as undocumented.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: lookmlDiffRun,
+}
+
+func lookmlDiffRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+	lkmlDir, err := getLookMLDir(isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	if lookmlCompile {
+		opts := core.DbtOptions{Select: []string{name}, Compile: true, StatePath: viper.GetString("state-path")}
+		if err := core.CompileModel(cmd.Context(), opts, dbtDir, isVerbose); err != nil {
+			return fmt.Errorf("error compiling %s: %w", name, err)
+		}
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+
+	views, err := loadLookMLViews(lkmlDir)
+	if err != nil {
+		return err
+	}
+	project, dataset, table := nodeRelation(node)
+	matches := core.FindViewForTable(views, project, dataset, table)
+	if len(matches) == 0 {
+		return &core.NotFoundError{Kind: "LookML view for", Name: fmt.Sprintf("%s.%s.%s", project, dataset, table)}
+	}
+	if len(matches) > 1 {
+		core.ColorPrintln(core.Yellow, fmt.Sprintf("⚠ %d views use %s.%s, comparing the first", len(matches), dataset, table))
+	}
+	view := matches[0]
+
+	var columns []string
+	source := "schema.yml"
+	if !lookmlDryRun {
+		for key, c := range node.Columns {
+			if c.Name == "" {
+				columns = append(columns, key)
+			} else {
+				columns = append(columns, c.Name)
+			}
+		}
+	}
+	if len(columns) == 0 {
+		source = "dry run"
+		var cache *core.DryRunCache
+		if !lookmlNoCache {
+			if cache, err = core.NewDryRunCache(); err != nil {
+				return err
+			}
+		}
+		fields, err := dryRunSchema(cmd.Context(), manifest, name, dbtDir, cache, isVerbose)
+		if err != nil {
+			return err
+		}
+		for _, f := range core.FlattenSchema(fields) {
+			columns = append(columns, f.Name)
+		}
+	}
+
+	diff := core.DiffLookML(view.Fields(), columns)
+
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, fmt.Sprintf("LookML view %s vs model %s", view.Name, name))
+	core.ColorPrintln(core.Dim, fmt.Sprintf("%s:%d, columns from %s", relPath(lkmlDir, view.Path), view.Line, source))
+	fmt.Println()
+
+	// schema.yml may leave columns out, so only the dry run schema is complete
+	complete := source == "dry run"
+	missingColor, missingText, missingLabel := core.Red, "not in the model", "Missing from Model"
+	if !complete {
+		missingColor, missingText, missingLabel = core.Yellow, "not documented in schema.yml", "Not Documented"
+	}
+	for _, m := range diff.Missing {
+		core.ColorPrint(missingColor, "- ")
+		fmt.Printf("%s %s%s %s%s\n", m.Column,
+			core.Dim, m.Field.Type+" "+m.Field.Name, fmt.Sprintf("(line %d) %s", m.Field.Line, missingText), core.Reset)
+	}
+	for _, c := range diff.Unexposed {
+		core.ColorPrint(core.Yellow, "+ ")
+		fmt.Printf("%s %snot exposed in LookML%s\n", c, core.Dim, core.Reset)
+	}
+	if len(diff.Missing) == 0 && len(diff.Unexposed) == 0 {
+		core.ColorPrint(core.Bold+core.Green, "✓ ")
+		fmt.Println("every column is exposed and every reference exists")
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("Fields: %d\nModel Columns: %d\n%s: %s%d%s\nNot Exposed: %s%d%s",
+		len(view.Fields()), len(columns),
+		missingLabel, missingColor, len(diff.Missing), core.Reset,
+		core.Yellow, len(diff.Unexposed), core.Reset)
+	core.PrintBox("LookML Diff", summary, core.BoxDouble, core.BrightMagenta)
+
+	if len(diff.Missing) > 0 && !complete {
+		core.ColorPrintln(core.Dim, "pass --dry-run to check them against the model's actual columns")
+	}
+	if len(diff.Missing) > 0 && complete {
+		return &core.LookMLDriftError{View: view.Name, Model: name, Missing: len(diff.Missing)}
+	}
+	return nil
+}
+
+var lookmlPathCmd = &cobra.Command{
+	Use:   "path [model]",
+	Short: "Find the LookML views that select from a model's table",
+	Long: `Print the file and line of every LookML view whose sql_table_name or derived
+table SQL selects from the model's table (project.dataset.table or
+dataset.table, with or without backticks, and with the project or dataset
+templated by Liquid or a LookML constant).
+
+--files prints only the file paths, for piping into an editor.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: lookmlPathRun,
+}
+
+var lookmlModelCmd = &cobra.Command{
+	Use:   "model <view>",
+	Short: "Find the dbt models a LookML view selects from",
+	Args:  cobra.ExactArgs(1),
+	RunE:  lookmlModelRun,
+}
+
+func lookmlPathRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+	lkmlDir, err := getLookMLDir(isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+	views, err := loadLookMLViews(lkmlDir)
+	if err != nil {
+		return err
+	}
+
+	project, dataset, table := nodeRelation(node)
+	refs := core.FindTableReferences(views, project, dataset, table)
+	if len(refs) == 0 {
+		return &core.NotFoundError{Kind: "LookML view selecting from", Name: fmt.Sprintf("%s.%s.%s", project, dataset, table)}
+	}
+
+	printed := map[string]bool{}
+	for _, ref := range refs {
+		path := relPath(lkmlDir, ref.View.Path)
+		if lookmlFiles {
+			if !printed[path] {
+				printed[path] = true
+				fmt.Println(filepath.Join(lkmlDir, path))
+			}
+			continue
+		}
+		fmt.Printf("%s:%d %s%s%s %s(%s: %s)%s\n", path, ref.Line,
+			core.Bold, ref.View.Name, core.Reset,
+			core.Dim, ref.Key, ref.Text, core.Reset)
+	}
+	return nil
+}
+
+func lookmlModelRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+	lkmlDir, err := getLookMLDir(isVerbose)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	views, err := loadLookMLViews(lkmlDir)
+	if err != nil {
+		return err
+	}
+
+	var named []core.LookMLView
+	for _, v := range views {
+		// refinements (+view) count as the view they refine
+		if strings.EqualFold(strings.TrimPrefix(v.Name, "+"), args[0]) {
+			named = append(named, v)
+		}
+	}
+	if len(named) == 0 {
+		return &core.NotFoundError{Kind: "LookML view", Name: args[0]}
+	}
+
+	found := false
+	for _, n := range manifest.Models() {
+		project, dataset, table := nodeRelation(n)
+		for _, ref := range core.FindTableReferences(named, project, dataset, table) {
+			found = true
+			fmt.Printf("%s%s%s %s%s%s %s(%s:%d %s)%s\n",
+				core.Bold, n.Name, core.Reset,
+				core.Cyan, n.OriginalFilePath, core.Reset,
+				core.Dim, relPath(lkmlDir, ref.View.Path), ref.Line, ref.Key, core.Reset)
+		}
+	}
+	if !found {
+		return &core.NotFoundError{Kind: "dbt model for view", Name: args[0]}
+	}
+	return nil
+}
+
+// getLookMLDir returns the LookML project from the "lookml-dir" key
+func getLookMLDir(b bool) (string, error) {
+	dir := viper.GetString("lookml-dir")
+	if dir == "" {
+		return "", &core.ConfigError{Msg: "lookml-dir is not set (pass --lookml-dir or add it to .dibbity.yaml)"}
+	}
+	dir, err := core.ExpandHome(dir)
+	if err != nil {
+		return "", &core.ConfigError{Msg: "failed to get user home directory", Err: err}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", &core.ConfigError{Msg: fmt.Sprintf("lookml-dir %q is not a directory", dir), Err: err}
+	}
+	core.LogVerbose(b, "Using LookML folder: %s", dir)
+	return dir, nil
+}
+
+// loadLookMLViews parses the LookML project, warning about files it can't parse
+func loadLookMLViews(dir string) ([]core.LookMLView, error) {
+	views, err := core.LoadLookMLViews(dir, func(path string, err error) {
+		fmt.Fprintln(os.Stderr, core.Yellow+fmt.Sprintf("⚠ skipping %s: %v", relPath(dir, path), err)+core.Reset)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading LookML in %s: %w", dir, err)
+	}
+	return views, nil
+}
+
+func relPath(base, path string) string {
+	if rel, err := filepath.Rel(base, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func init() {
+	rootCmd.AddCommand(lookmlCmd)
+	lookmlCmd.AddCommand(lookmlDiffCmd)
+	lookmlCmd.AddCommand(lookmlPathCmd)
+	lookmlCmd.AddCommand(lookmlModelCmd)
+
+	lookmlDiffCmd.Flags().BoolVar(&lookmlDryRun, "dry-run", false, "Compare with the dry run schema instead of schema.yml")
+	lookmlDiffCmd.Flags().BoolVarP(&lookmlCompile, "compile", "c", false, "Compile the model first")
+	lookmlDiffCmd.Flags().BoolVar(&lookmlNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	registerModelCompletion(lookmlDiffCmd, false)
+
+	lookmlPathCmd.Flags().BoolVar(&lookmlFiles, "files", false, "Print only the paths of matching files")
+	registerModelCompletion(lookmlPathCmd, false)
+}
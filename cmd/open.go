@@ -8,7 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/spf13/viper"
-	"log"
+	"net/url"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -23,58 +23,90 @@ var openCmd = &cobra.Command{
 	Long: `
  	Open selected models in BigQuery Studio
 `,
-	Run: openCmdRun,
+	RunE: openCmdRun,
 }
 
 type bqUrlBuilder struct {
-	baseUrl     string
+	urlTemplate string
 	projectID   string
 	datasetName string
 	tableName   string
 }
 
-func openCmdRun(cmd *cobra.Command, args []string) {
+func openCmdRun(cmd *cobra.Command, args []string) error {
 	// TODO: add some verbose printing
 	isVerbose := viper.GetBool("verbose")
 	dbtDir, err := core.GetFolder(isVerbose)
 
 	if err != nil {
-		log.Fatalf("Error getting dbt folder: %v", err)
+		return fmt.Errorf("error getting dbt folder: %w", err)
 	}
 
 	selectedModels = append(selectedModels, args...)
 	if len(selectedModels) == 0 {
-		log.Fatalln("No models selected.")
+		selectedModels, err = pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
 	}
 
 	if len(selectedModels) > 1 {
-		log.Fatalln("Only one model can be opened at a time.")
+		return errors.New("only one model can be opened at a time")
 	}
 
 	selectedModel := selectedModels[0]
 
-	fp, err := core.FindFilepath(selectedModel, dbtDir, "models", isVerbose)
+	bqb, err := modelRelation(selectedModel, dbtDir, isVerbose)
 	if err != nil {
-		log.Fatalf("Error finding model %s: %v", selectedModel, err)
+		return err
 	}
 
-	bqb, err := formatModelPath(fp)
-	if err != nil {
-		log.Fatalf("Error formatting model path: %v", err)
+	bqb.urlTemplate = viper.GetString("bq-url-template")
+	if bqb.urlTemplate == "" {
+		bqb.urlTemplate = core.DefaultBqURLTemplate
+	}
+	if bqb.projectID == "" {
+		bqb.projectID = viper.GetString("bq-project")
+	}
+	if bqb.projectID == "" {
+		return &core.ConfigError{Msg: "no BigQuery project for " + selectedModel + ", set bq-project"}
 	}
-
-	// TODO: set these as parameters in dibbity.yml
-	bqb.baseUrl = "https://console.cloud.google.com/bigquery"
-	bqb.projectID = "data-trustedwarehouse-p"
 	url := getUrl(bqb)
+	core.LogVerbose(isVerbose, "URL: %s", url)
 
 	summary := fmt.Sprintf("%sOpening %s in browser%s", core.Magenta, bqb.tableName, core.Reset)
 	fmt.Println(summary)
 
 	if err := openBrowser(url); err != nil {
-		log.Fatalf("Error opening browser: %v", err)
+		return fmt.Errorf("error opening browser: %w", err)
 	}
 
+	return nil
+}
+
+// modelRelation finds where a model is built, from the manifest when there is
+// one, falling back to the dags/templates/models/<dataset parts>/<table> layout
+func modelRelation(name string, dbtDir string, b bool) (bqUrlBuilder, error) {
+	if manifest, err := core.LoadManifest(dbtDir, b); err == nil {
+		if node, err := manifest.Model(name); err == nil && node.Schema != "" {
+			table := node.Alias
+			if table == "" {
+				table = node.Name
+			}
+			return bqUrlBuilder{projectID: node.Database, datasetName: node.Schema, tableName: table}, nil
+		}
+	}
+
+	fp, err := core.FindFilepath(name, dbtDir, "models", b)
+	if err != nil {
+		return bqUrlBuilder{}, fmt.Errorf("error finding model %s: %w", name, err)
+	}
+
+	bqb, err := formatModelPath(fp)
+	if err != nil {
+		return bqUrlBuilder{}, fmt.Errorf("error formatting model path: %w", err)
+	}
+	return bqb, nil
 }
 
 func formatModelPath(modelPath string) (bqUrlBuilder, error) {
@@ -107,9 +139,13 @@ func formatModelPath(modelPath string) (bqUrlBuilder, error) {
 
 func getUrl(bqb bqUrlBuilder) string {
 
-	s := fmt.Sprintf("%s?p=%s&d=%s&t=%s&page=table", bqb.baseUrl, bqb.projectID, bqb.datasetName, bqb.tableName)
+	r := strings.NewReplacer(
+		"{project}", url.QueryEscape(bqb.projectID),
+		"{dataset}", url.QueryEscape(bqb.datasetName),
+		"{table}", url.QueryEscape(bqb.tableName),
+	)
 
-	return s
+	return r.Replace(bqb.urlTemplate)
 }
 
 func openBrowser(url string) error {
@@ -132,10 +168,8 @@ func openBrowser(url string) error {
 func init() {
 	rootCmd.AddCommand(openCmd)
 
-	openCmd.Flags().StringSliceVarP(&selectedModels, "select", "s", []string{}, "Select model to open")
-	if err := dryRunCmd.MarkFlagRequired("select"); err != nil {
-		log.Fatalf("Error: could not mark --select flag as required: %v", err)
-	}
+	openCmd.Flags().StringSliceVarP(&selectedModels, "select", "s", []string{}, "Select model to open (opens a picker if omitted)")
+	registerModelCompletion(openCmd, false)
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,37 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// errNoSelection is returned when a command needs models but got none and can't ask
+var errNoSelection = errors.New("no models selected (pass --select or run in a terminal to pick interactively)")
+
+// pickModels opens the fuzzy model picker when a command gets no selection.
+// It only runs when stdin is a terminal and the picker isn't disabled with --picker=false.
+func pickModels(dbtDir string, multi bool, b bool) ([]string, error) {
+	if !viper.GetBool("picker") || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errNoSelection
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, b)
+	if err != nil {
+		return nil, fmt.Errorf("error loading models for picker: %w", err)
+	}
+
+	names, err := core.PickModels(core.PickerItemsFromManifest(manifest), multi)
+	if err != nil {
+		return nil, err
+	}
+	core.LogVerbose(b, "Picked models: %v", names)
+	return names, nil
+}
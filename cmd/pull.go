@@ -0,0 +1,216 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	pullTo      string
+	pullLimit   int
+	pullWhere   string
+	pullFormat  string
+	pullYes     bool
+	pullNoCache bool
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull <selector>",
+	Short: "Copy model data into a local DuckDB database or Parquet/CSV files",
+	Long: `Copy the tables built by the selected models into a local DuckDB database
+(--to local.duckdb) or a directory of Parquet or CSV files (--to dir/).
+
+Each table is dry run first to show how much data the extract scans; above
+confirm-bytes you're asked to confirm (or pass --yes). Rows are read with
+bq and written by the duckdb CLI, with column types matching the BigQuery
+schema, so both need to be on $PATH. Set bq-api to extract from a local
+BigQuery emulator instead.
+
+bq can't page query results, so every row of a table is held in memory while
+it's copied. A table with more than 1,000,000 rows fails to pull unless --limit
+is set; pull bigger tables in slices with --where. Note that --limit doesn't
+reduce the bytes BigQuery scans, --where on a partition or cluster column does.
+
+Files are named dataset.table.parquet (or .csv), and DuckDB tables
+dataset.table, so two models can only be pulled together if their tables differ
+in dataset or name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: pullRun,
+}
+
+// pullTable is a model table being pulled, with its extract query
+type pullTable struct {
+	Model
+	Dataset string
+	Table   string
+	Fields  []core.SchemaField
+}
+
+func pullRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	if pullTo == "" {
+		return &core.ConfigError{Msg: "--to is required, e.g. --to local.duckdb or --to data/"}
+	}
+	toDuckDB := isDuckDBPath(pullTo)
+	format := strings.ToLower(pullFormat)
+	if !toDuckDB && format != "parquet" && format != "csv" {
+		return &core.ConfigError{Msg: fmt.Sprintf("unknown --format %q, expected parquet or csv", pullFormat)}
+	}
+	if pullLimit < 0 || pullLimit > core.MaxPullRows {
+		return &core.ConfigError{Msg: fmt.Sprintf("--limit must be between 0 and %d", core.MaxPullRows)}
+	}
+	threshold, err := core.ParseBytes(viper.GetString("confirm-bytes"))
+	if err != nil {
+		return fmt.Errorf("--confirm-bytes: %w", err)
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	nodes, err := selectNodes(cmd.Context(), manifest, args[0], dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var cache *core.DryRunCache
+	if !pullNoCache {
+		if cache, err = core.NewDryRunCache(); err != nil {
+			return err
+		}
+	}
+
+	var tables []pullTable
+	var totalCost int64
+	pulled := map[string]string{} // dataset.table → model, as tables and files are named
+	for _, node := range nodes {
+		if node.Config.Materialized == "ephemeral" {
+			core.ColorPrintln(core.Yellow, fmt.Sprintf("⚠ skipping ephemeral model %s, it has no table", node.Name))
+			continue
+		}
+		project, dataset, table := nodeRelation(node)
+		key := strings.ToLower(dataset + "." + table)
+		if other, ok := pulled[key]; ok {
+			return &core.ConfigError{Msg: fmt.Sprintf("%s and %s both build a table named %s.%s, pull them separately", other, node.Name, dataset, table)}
+		}
+		pulled[key] = node.Name
+		t := pullTable{
+			Model:   Model{Name: node.Name, Materialized: node.Config.Materialized, SQL: core.PullQuery(project, dataset, table, pullWhere, pullLimit)},
+			Dataset: dataset,
+			Table:   table,
+		}
+
+		t.BQRunner = core.BqRunner{Query: t.SQL, Ok: true}
+		if _, err := t.BQRunner.BqDryRunCached(cmd.Context(), cache, isVerbose); err != nil {
+			return fmt.Errorf("error running dry run: %w", err)
+		}
+		if !t.BQRunner.Ok {
+			core.PrintBox("Error", t.BQRunner.RespError, core.BoxRounded, core.Red)
+			return &core.BqError{Msg: fmt.Sprintf("dry run of %s.%s.%s failed", project, dataset, table)}
+		}
+		if t.Fields, err = core.ParseDryRunSchema(t.BQRunner.Out); err != nil {
+			return err
+		}
+		t.CostBytes = int(t.BQRunner.BytesProcessed)
+		totalCost += t.BQRunner.BytesProcessed
+		tables = append(tables, t)
+	}
+	if len(tables) == 0 {
+		return &core.NotFoundError{Kind: "tables to pull for", Name: args[0]}
+	}
+
+	var rows [][]string
+	for _, t := range tables {
+		rows = append(rows, []string{t.Name, t.Dataset + "." + t.Table, fmt.Sprint(len(t.Fields)), FormatCost(t.CostBytes)})
+	}
+	fmt.Println()
+	core.PrintTable([]string{"MODEL", "TABLE", "COLUMNS", "DATA TO PROCESS"}, rows, 60)
+	fmt.Println()
+	summary := fmt.Sprintf("Tables: %d\nTotal Data to Process: %s", len(tables), core.FormatBytes(totalCost))
+	if price := viper.GetFloat64("price-per-tib"); price > 0 {
+		summary += fmt.Sprintf("\nEstimated Cost: %s", core.FormatPrice(totalCost, price))
+	}
+	core.PrintBox("Pull to "+pullTo, summary, core.BoxRounded, core.BrightCyan)
+	fmt.Println()
+
+	if threshold > 0 && totalCost > threshold && !pullYes {
+		if !core.Confirm(fmt.Sprintf("Scan %s to pull %d table(s)?", core.StripANSI(core.FormatBytes(totalCost)), len(tables)), false) {
+			core.ColorPrintln(core.Dim, "pass --yes to pull without asking")
+			return &core.BudgetExceededError{Limit: threshold, Actual: totalCost}
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "dibbity-pull-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, t := range tables {
+		core.ColorPrint(core.Bold+core.BrightBlue, "⇣ ")
+		fmt.Printf("Pulling %s.%s...\n", t.Dataset, t.Table)
+
+		rowsPath := filepath.Join(tmp, t.Dataset+"."+t.Table+".json")
+		n, err := core.ExtractRows(cmd.Context(), t.SQL, pullLimit, rowsPath, isVerbose)
+		if errors.Is(err, core.ErrTooManyRows) {
+			return &core.ConfigError{Msg: fmt.Sprintf("%s has %v, pull it in slices with --where or pass --limit", t.Name, err)}
+		}
+		if err != nil {
+			return fmt.Errorf("error extracting %s: %w", t.Name, err)
+		}
+
+		dest := pullTo
+		if toDuckDB {
+			err = core.DuckDBLoad(cmd.Context(), pullTo, t.Dataset, t.Table, rowsPath, t.Fields, isVerbose)
+			dest += fmt.Sprintf(" (%s.%s)", t.Dataset, t.Table)
+		} else {
+			dest = filepath.Join(pullTo, t.Dataset+"."+t.Table+"."+format)
+			err = core.DuckDBExport(cmd.Context(), dest, format, rowsPath, t.Fields, isVerbose)
+		}
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", t.Name, err)
+		}
+		core.ColorPrint(core.Bold+core.Green, "✓ ")
+		fmt.Printf("%s → %s (%d rows)\n", t.Name, dest, n)
+	}
+	return nil
+}
+
+// isDuckDBPath reports whether --to names a DuckDB database rather than a directory
+func isDuckDBPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".duckdb", ".ddb", ".db":
+		return true
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(pullCmd)
+
+	pullCmd.Flags().StringVarP(&pullTo, "to", "t", "", "DuckDB database (.duckdb, .db) or directory to write files to")
+	pullCmd.Flags().IntVarP(&pullLimit, "limit", "n", 0, "Maximum rows per table, at most 1,000,000; 0 for all, failing on tables with more")
+	pullCmd.Flags().StringVar(&pullWhere, "where", "", "SQL filter applied to each table, e.g. \"date >= '2025-01-01'\"")
+	pullCmd.Flags().StringVarP(&pullFormat, "format", "f", "parquet", "File format when --to is a directory: parquet or csv")
+	pullCmd.Flags().BoolVarP(&pullYes, "yes", "y", false, "Don't ask for confirmation above --confirm-bytes")
+	pullCmd.Flags().BoolVar(&pullNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	pullCmd.Flags().String("confirm-bytes", "1GB", "Ask for confirmation when the extract scans more than this (0 to never ask)")
+	cobra.CheckErr(viper.BindPFlag("confirm-bytes", pullCmd.Flags().Lookup("confirm-bytes")))
+	_ = pullCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"parquet", "csv"}, cobra.ShellCompDirectiveNoFileComp))
+	registerModelCompletion(pullCmd, true)
+}
@@ -4,10 +4,18 @@ Copyright © 2025 Matthew Thornton
 package cmd
 
 import (
+	"context"
+	"dibbity/core"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -22,18 +30,53 @@ examples and usage of using your application. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+to quickly create a Cobra application.
+
+Exit codes:
+  0  success
+  1  unclassified error or bad usage
+  2  configuration error
+  3  dbt error
+  4  bq error, or one or more models failed to dry run
+  5  model or file not found
+  6  dry run exceeded the --max-bytes budget
+  7  timed out (--timeout, --dbt-timeout, --bq-timeout)
+  8  schema-check found documented columns that don't match the output, or
+     lookml diff found fields referencing columns the model doesn't have
+  130 interrupted by SIGINT / SIGTERM`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// flags parsed fine, so any error from here on is not a usage error
+		cmd.SilenceUsage = true
+
+		// config show / validate still run so the broken project can be diagnosed
+		if projectErr != nil && cmd.Parent() != configCmd {
+			return projectErr
+		}
+
+		var ctx context.Context
+		ctx, cancelTimeout = core.WithTimeout(cmd.Context(), "command", viper.GetDuration("timeout"))
+		cmd.SetContext(ctx)
+		return nil
+	},
 }
 
+// cancelTimeout releases the global --timeout context once the command returns
+var cancelTimeout context.CancelFunc = func() {}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The process exits with the code mapped from the returned error, see core.ExitCode.
 func Execute() {
-	err := rootCmd.Execute()
+	// cancel the context on SIGINT / SIGTERM so running subprocesses get killed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(core.ExitCode(err))
 	}
 }
 
@@ -45,17 +88,47 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.dibbity.yaml)")
-	rootCmd.PersistentFlags().String("dbt-dir", "/tmp", "Path to dbt dir")
+	rootCmd.PersistentFlags().String("project", "", "Named project from the projects: section of .dibbity.yaml")
+	rootCmd.PersistentFlags().String("dbt-dir", "", "Path to dbt dir (default is the dbt project containing the working directory)")
+	rootCmd.PersistentFlags().String("runner", "poetry", "How to run dbt: poetry, uv, pipenv or none")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Verbose output")
-	err := viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
-	if err != nil {
-		return
-	}
+	rootCmd.PersistentFlags().Bool("picker", true, "Open the interactive model picker when no models are selected")
+
+	rootCmd.PersistentFlags().String("bq-project", "", "GCP project bq runs dry runs in")
+	rootCmd.PersistentFlags().String("bq-location", "", "BigQuery location, e.g. EU or US")
+	rootCmd.PersistentFlags().String("bq-api", "", "BigQuery API endpoint bq talks to, e.g. a local emulator (empty for Google's)")
+	rootCmd.PersistentFlags().String("bq-url-template", core.DefaultBqURLTemplate, "URL `open` uses, with {project}, {dataset} and {table} placeholders")
+	rootCmd.PersistentFlags().Float64("price-per-tib", 0, "On-demand price per TiB scanned, to estimate dry run costs (0 to hide)")
+	rootCmd.PersistentFlags().String("state-path", "target_prod", "Production artefacts used with --defer")
+	rootCmd.PersistentFlags().String("lookml-dir", "", "Path to the LookML project the lookml commands read views from")
+
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Timeout for the whole command, e.g. 5m (0 for none)")
+	rootCmd.PersistentFlags().Duration("dbt-timeout", 0, "Timeout for each dbt invocation (0 for none)")
+	rootCmd.PersistentFlags().Duration("bq-timeout", 0, "Timeout for each bq invocation (0 for none)")
+	rootCmd.PersistentFlags().Int("bq-retries", 3, "Retries for transient bq failures (rate limits, backend errors)")
+	rootCmd.PersistentFlags().Duration("bq-retry-backoff", time.Second, "Initial backoff between bq retries, doubled each retry")
+	rootCmd.PersistentFlags().Duration("bq-retry-max-backoff", 30*time.Second, "Maximum backoff between bq retries")
+
+	rootCmd.PersistentFlags().String("cache-dir", "", "Dry run cache location (default is the user cache dir)")
+	rootCmd.PersistentFlags().Duration("cache-ttl", 24*time.Hour, "How long cached dry run results are valid")
+
+	bindFlags(rootCmd.PersistentFlags())
+}
+
+// bindFlags binds every flag in fs to the viper key of the same name, so each
+// setting resolves as flag > DIBBITY_* env > project config > home config > default
+func bindFlags(fs *pflag.FlagSet) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		if f.Name == "config" {
+			return
+		}
+		cobra.CheckErr(viper.BindPFlag(f.Name, f))
+	})
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -74,11 +147,131 @@ func initConfig() {
 		viper.SetConfigName(".dibbity")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match, e.g. DIBBITY_DBT_DIR for dbt-dir
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
 
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+		addConfigLayer(viper.ConfigFileUsed())
+	} else {
+		configErr = err
+	}
+
+	// a .dibbity.yaml at the root of the dbt project we're in merges over the home
+	// config, and the project itself beats the home config's dbt-dir
+	root, inProject := core.FindProjectRoot(".")
+	var local *viper.Viper
+	if inProject {
+		path := filepath.Join(root, ".dibbity.yaml")
+		if _, err := os.Stat(path); err == nil && path != viper.ConfigFileUsed() {
+			if local, err = readConfigFile(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading project config %s: %v\n", path, err)
+			} else {
+				fmt.Fprintln(os.Stderr, "Using project config file:", path)
+				// merged now for any projects: it defines, and again below
+				cobra.CheckErr(viper.MergeConfigMap(local.AllSettings()))
+			}
+		}
+		found := map[string]any{"dbt-dir": root}
+		cobra.CheckErr(viper.MergeConfigMap(found))
+		addSettingsLayer(workingDirLayer, found)
+	}
+
+	activateProject(projectName(root, inProject, local))
+
+	// the project-local file beats the named project's settings from the home config
+	if local != nil {
+		cobra.CheckErr(viper.MergeConfigMap(local.AllSettings()))
+		configLayers = append(configLayers, configLayer{path: local.ConfigFileUsed(), v: local, file: true})
+	}
+}
+
+// projectErr is set when --project names a project that isn't configured
+var projectErr error
+
+// projectName picks the named project to activate. --project or DIBBITY_PROJECT
+// always wins. Inside a dbt project it's the project key of the project's own
+// .dibbity.yaml, or else the configured project with that dbt-dir, so a default
+// project in the home config only applies outside any dbt project.
+func projectName(root string, inProject bool, local *viper.Viper) string {
+	if f := rootCmd.PersistentFlags().Lookup("project"); f.Changed {
+		return f.Value.String()
+	}
+	if name := os.Getenv(envPrefix + "_PROJECT"); name != "" {
+		return name
+	}
+	if !inProject {
+		return viper.GetString("project")
+	}
+	if local != nil && local.GetString("project") != "" {
+		return local.GetString("project")
+	}
+	name, _ := core.ProjectForDir(root)
+	return name
+}
+
+// activateProject merges the named project's settings over the loaded config
+func activateProject(name string) {
+	if name == "" {
+		return
+	}
+	settings, err := core.ActivateProject(name)
+	if err != nil {
+		projectErr = err
+		return
+	}
+	addSettingsLayer("projects."+name, settings)
+	core.LogVerbose(viper.GetBool("verbose"), "Using project %s", name)
+}
+
+// configErr keeps why the config file could not be read, for `config validate`
+var configErr error
+
+// envPrefix namespaces the environment variables viper reads
+const envPrefix = "DIBBITY"
+
+// workingDirLayer labels the dbt-dir found by walking up from the working directory
+const workingDirLayer = "found from working directory"
+
+// configLayer is one source of settings, in load order. Only file layers are
+// config files; the others come from the working directory or a named project.
+type configLayer struct {
+	path string
+	v    *viper.Viper
+	file bool
+}
+
+var configLayers []configLayer
+
+// addSettingsLayer records settings already merged into viper as a layer labelled path
+func addSettingsLayer(path string, settings map[string]any) {
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return
+	}
+	configLayers = append(configLayers, configLayer{path: path, v: v})
+}
+
+func addConfigLayer(path string) error {
+	v, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	configLayers = append(configLayers, configLayer{path: path, v: v, file: true})
+	return nil
+}
+
+// readConfigFile reads a YAML config file on its own, without merging it
+func readConfigFile(path string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
 	}
+	return v, nil
 }
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// resetConfig clears viper, the recorded layers and every persistent flag so
+// each test loads config from scratch
+func resetConfig(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		viper.Reset()
+		configLayers = nil
+		configErr, projectErr = nil, nil
+		cfgFile = ""
+		rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+			_ = f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+		bindFlags(rootCmd.PersistentFlags())
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// configEnv makes a temp HOME and a dbt project beside it, with no DIBBITY_*
+// variables set, returning both
+func configEnv(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	homeDir := filepath.Join(dir, "home")
+	project := filepath.Join(dir, "warehouse")
+	writeFile(t, filepath.Join(project, "dbt_project.yml"), "name: warehouse\n")
+	if err := os.MkdirAll(homeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", homeDir)
+	for _, key := range []string{"PROJECT", "BQ_PROJECT", "DBT_DIR", "RUNNER", "STATE_PATH"} {
+		t.Setenv(envPrefix+"_"+key, "")
+		os.Unsetenv(envPrefix + "_" + key)
+	}
+	return homeDir, project
+}
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		name    string
+		inside  bool   // run from the warehouse dbt project
+		local   string // the warehouse project's .dibbity.yaml
+		flag    string
+		env     string
+		want    string
+		wantBqP string
+	}{
+		{name: "home default outside a project", want: "marketing", wantBqP: "marketing-p"},
+		{name: "dir project beats home default", inside: true, want: "warehouse", wantBqP: "warehouse-p"},
+		{name: "local project key beats dir", inside: true, local: "project: marketing\n", want: "marketing", wantBqP: "marketing-p"},
+		{name: "flag beats dir", inside: true, flag: "marketing", want: "marketing", wantBqP: "marketing-p"},
+		{name: "env beats dir", inside: true, env: "marketing", want: "marketing", wantBqP: "marketing-p"},
+		{name: "flag beats env", inside: true, flag: "warehouse", env: "marketing", want: "warehouse", wantBqP: "warehouse-p"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home, project := configEnv(t)
+			writeFile(t, filepath.Join(home, ".dibbity.yaml"), `project: marketing
+projects:
+  warehouse:
+    dbt-dir: `+project+`
+    bq-project: warehouse-p
+  marketing:
+    dbt-dir: /nowhere/marketing
+    bq-project: marketing-p
+`)
+			if tt.local != "" {
+				writeFile(t, filepath.Join(project, ".dibbity.yaml"), tt.local)
+			}
+			if tt.inside {
+				t.Chdir(project)
+			} else {
+				t.Chdir(home)
+			}
+			if tt.env != "" {
+				t.Setenv("DIBBITY_PROJECT", tt.env)
+			}
+			resetConfig(t)
+			if tt.flag != "" {
+				if err := rootCmd.PersistentFlags().Set("project", tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			initConfig()
+
+			if projectErr != nil {
+				t.Fatalf("projectErr = %v", projectErr)
+			}
+			if got := activeLayer(); got != "projects."+tt.want {
+				t.Errorf("active project layer = %q, want projects.%s", got, tt.want)
+			}
+			if got := viper.GetString("bq-project"); got != tt.wantBqP {
+				t.Errorf("bq-project = %q, want %q", got, tt.wantBqP)
+			}
+		})
+	}
+}
+
+// activeLayer returns the label of the named project layer, if any
+func activeLayer() string {
+	for _, l := range configLayers {
+		if !l.file && l.path != workingDirLayer {
+			return l.path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	schemaCheckSelect  []string
+	schemaCheckFix     bool
+	schemaCheckCompile bool
+	schemaCheckNoCache bool
+)
+
+var schemaCheckCmd = &cobra.Command{
+	Use:   "schema-check",
+	Short: "Compare documented columns against the models' real output schema",
+	Long: `Compare the columns and data_type documented in schema.yml against the
+result schema of a BigQuery dry run of each model, and report columns that are
+missing (documented but not output), extra (output but not documented) or have
+a different type.
+
+Checks every model of the project unless --select is given. Exits with code 8
+when drift is found, so it can gate CI. --fix adds stubs for the extra columns
+to the model's YAML (or a schema.yml next to the model if it has none).`,
+	Args: cobra.NoArgs,
+	RunE: schemaCheckRun,
+}
+
+func schemaCheckRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	if schemaCheckCompile {
+		opts := core.DbtOptions{Select: schemaCheckSelect, Compile: true, StatePath: viper.GetString("state-path")}
+		if err := core.CompileModel(cmd.Context(), opts, dbtDir, isVerbose); err != nil {
+			return fmt.Errorf("error compiling models: %w", err)
+		}
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if len(schemaCheckSelect) > 0 {
+		names, err = core.ListModels(cmd.Context(), schemaCheckSelect, dbtDir, isVerbose)
+		if err != nil {
+			return fmt.Errorf("error running dbt ls: %w", err)
+		}
+	} else {
+		for _, n := range manifest.Models() {
+			if n.PackageName == manifest.Metadata.ProjectName {
+				names = append(names, n.Name)
+			}
+		}
+	}
+
+	var cache *core.DryRunCache
+	if !schemaCheckNoCache {
+		if cache, err = core.NewDryRunCache(); err != nil {
+			return err
+		}
+	}
+
+	// extra columns to stub, per YAML file and model
+	fixes := map[string]map[string][]core.SchemaField{}
+	var failed []string
+	driftModels, problems, remaining := 0, 0, 0
+	checked := 0
+
+	for _, name := range names {
+		node, err := manifest.Model(name)
+		if err != nil {
+			return err
+		}
+		if node.Config.Materialized == "ephemeral" {
+			core.LogVerbose(isVerbose, "Skipping ephemeral model %s", name)
+			continue
+		}
+
+		fields, err := dryRunSchema(cmd.Context(), manifest, name, dbtDir, cache, isVerbose)
+		if err != nil {
+			core.ColorPrint(core.Bold+core.Red, "✗ ")
+			fmt.Printf("%s: %v\n", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		checked++
+
+		drift := core.CompareColumns(node.Columns, fields)
+		if drift.Count() == 0 {
+			core.ColorPrint(core.Bold+core.Green, "✓ ")
+			fmt.Println(name)
+			continue
+		}
+
+		driftModels++
+		problems += drift.Count()
+		remaining += len(drift.Missing) + len(drift.Mismatched)
+		core.ColorPrint(core.Bold+core.Yellow, "⚠ ")
+		core.ColorPrint(core.Bold, name)
+		core.ColorPrintln(core.Dim, " "+node.OriginalFilePath)
+		for _, f := range drift.Extra {
+			core.ColorPrint(core.Green, "    + ")
+			fmt.Printf("%s %s%s%s not documented\n", f.Name, core.Dim, f.SQLType(), core.Reset)
+		}
+		for _, c := range drift.Missing {
+			core.ColorPrint(core.Red, "    - ")
+			fmt.Printf("%s documented but not in the output\n", c)
+		}
+		for _, m := range drift.Mismatched {
+			core.ColorPrint(core.Yellow, "    ~ ")
+			fmt.Printf("%s documented as %s, output is %s\n", m.Column, m.Documented, m.Actual)
+		}
+
+		if len(drift.Extra) == 0 {
+			continue
+		}
+		if !schemaCheckFix {
+			remaining += len(drift.Extra)
+			continue
+		}
+		path := node.PatchFile(dbtDir)
+		if path == "" {
+			path = filepath.Join(dbtDir, filepath.Dir(node.OriginalFilePath), "schema.yml")
+		}
+		if fixes[path] == nil {
+			fixes[path] = map[string][]core.SchemaField{}
+		}
+		fixes[path][name] = drift.Extra
+	}
+
+	stubbed, err := writeColumnStubs(fixes, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	summary := fmt.Sprintf(
+		"Models Checked: %d\n"+
+			"With Drift: %s%d%s\n"+
+			"Problems: %d",
+		checked,
+		core.Yellow, driftModels, core.Reset,
+		problems,
+	)
+	if len(failed) > 0 {
+		summary += fmt.Sprintf("\nFailed to Dry Run: %s%d%s", core.Red, len(failed), core.Reset)
+	}
+	if schemaCheckFix {
+		summary += fmt.Sprintf("\nColumns Stubbed: %s%d%s", core.Green, stubbed, core.Reset)
+	}
+	fmt.Println()
+	core.PrintBox("Schema Check", summary, core.BoxDouble, core.BrightMagenta)
+
+	if len(failed) > 0 {
+		return &core.BqError{Msg: fmt.Sprintf("%d model(s) failed to dry run: %s", len(failed), strings.Join(failed, ", "))}
+	}
+	if remaining > 0 {
+		return &core.SchemaDriftError{Models: driftModels, Problems: remaining}
+	}
+	return nil
+}
+
+// writeColumnStubs adds name / data_type / description stubs for columns to
+// each model's YAML file, and returns how many were added
+func writeColumnStubs(fixes map[string]map[string][]core.SchemaField, b bool) (int, error) {
+	paths := make([]string, 0, len(fixes))
+	for p := range fixes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	stubbed := 0
+	for _, path := range paths {
+		f, err := core.LoadSchemaFile(path)
+		if err != nil {
+			return stubbed, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		models := make([]string, 0, len(fixes[path]))
+		for model := range fixes[path] {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+		for _, model := range models {
+			stubbed += f.StubColumns(model, fixes[path][model])
+		}
+		if err := f.Save(); err != nil {
+			return stubbed, fmt.Errorf("error writing %s: %w", path, err)
+		}
+		core.LogVerbose(b, "Wrote column stubs to %s", path)
+		core.ColorPrint(core.Bold+core.Green, "✓ ")
+		fmt.Printf("Stubbed columns in %s\n", path)
+	}
+	return stubbed, nil
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCheckCmd)
+
+	schemaCheckCmd.Flags().StringSliceVarP(&schemaCheckSelect, "select", "s", []string{}, "Select models to check (default is every model in the project)")
+	schemaCheckCmd.Flags().BoolVar(&schemaCheckFix, "fix", false, "Add stubs for undocumented columns to the models' YAML")
+	schemaCheckCmd.Flags().BoolVarP(&schemaCheckCompile, "compile", "c", false, "Compile the models first")
+	schemaCheckCmd.Flags().BoolVar(&schemaCheckNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	registerModelCompletion(schemaCheckCmd, true)
+}
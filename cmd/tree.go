@@ -0,0 +1,84 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	treeUp    bool
+	treeDown  bool
+	treeDepth int
+	treeASCII bool
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree [model]",
+	Short: "Print a model's parents or children as a tree",
+	Long: `Print the parents (--up, the default) or children (--down) of a model from
+target/manifest.json as a tree, with each node's materialization and tags.
+
+A node reached through more than one path is expanded the first time and shown
+as a back-reference after that. --depth limits how many levels are printed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: treeRun,
+}
+
+func treeRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		return err
+	}
+	node, err := manifest.Model(name)
+	if err != nil {
+		return err
+	}
+
+	boxType := core.BoxRounded
+	if treeASCII {
+		boxType = core.BoxSimple
+	}
+
+	title := "Upstream of " + name
+	if treeDown {
+		title = "Downstream of " + name
+	}
+	fmt.Println()
+	core.ColorPrintln(core.Bold+core.BrightBlue, title)
+	fmt.Print(core.RenderTree(manifest.DependencyTree(node.UniqueID, !treeDown, treeDepth), boxType, core.Dim))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(treeCmd)
+
+	treeCmd.Flags().BoolVar(&treeUp, "up", false, "Show parents (default)")
+	treeCmd.Flags().BoolVar(&treeDown, "down", false, "Show children")
+	treeCmd.Flags().IntVarP(&treeDepth, "depth", "d", -1, "Levels to print, -1 for all")
+	treeCmd.Flags().BoolVar(&treeASCII, "ascii", false, "Draw the tree with plain ASCII characters")
+	treeCmd.MarkFlagsMutuallyExclusive("up", "down")
+	registerModelCompletion(treeCmd, false)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2025 Matthew Thornton
+*/
+package cmd
+
+import (
+	"dibbity/core"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	yamlCompile bool
+	yamlNoCache bool
+	yamlPrint   bool
+)
+
+var yamlCmd = &cobra.Command{
+	Use:   "yaml [model]",
+	Short: "Generate schema.yml column stubs from a model's dry run schema",
+	Long: `Dry run a model's compiled SQL and write its result schema, including
+nested RECORD and REPEATED fields, as a models: entry in the schema.yml next
+to the model file.
+
+If the model is already documented its entry is merged: only new columns are
+added, and existing descriptions, data types, tests and comments are kept.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: yamlRun,
+}
+
+func yamlRun(cmd *cobra.Command, args []string) error {
+	isVerbose := viper.GetBool("verbose")
+	dbtDir, err := core.GetFolder(isVerbose)
+	if err != nil {
+		return fmt.Errorf("error getting dbt folder: %w", err)
+	}
+
+	var name string
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		picked, err := pickModels(dbtDir, false, isVerbose)
+		if err != nil {
+			return err
+		}
+		name = picked[0]
+	}
+
+	if yamlCompile {
+		opts := core.DbtOptions{Select: []string{name}, Compile: true, StatePath: viper.GetString("state-path")}
+		if err := core.CompileModel(cmd.Context(), opts, dbtDir, isVerbose); err != nil {
+			return fmt.Errorf("error compiling %s: %w", name, err)
+		}
+	}
+
+	// a brand new model may not be in the manifest yet
+	var node *core.Node
+	manifest, err := core.LoadManifest(dbtDir, isVerbose)
+	if err != nil {
+		core.LogVerbose(isVerbose, "Could not load manifest, falling back to searching target/: %v", err)
+		manifest = nil
+	} else if node, err = manifest.Model(name); err != nil {
+		manifest = nil
+	}
+
+	var cache *core.DryRunCache
+	if !yamlNoCache {
+		if cache, err = core.NewDryRunCache(); err != nil {
+			return err
+		}
+	}
+
+	fields, err := dryRunSchema(cmd.Context(), manifest, name, dbtDir, cache, isVerbose)
+	if err != nil {
+		return err
+	}
+
+	// merge into the file that already documents the model, so dbt doesn't see two patches
+	path, found := "", false
+	if node != nil && node.PatchFile(dbtDir) != "" {
+		path, found = node.PatchFile(dbtDir), true
+	} else {
+		modelPath, err := core.FindFilepath(name, dbtDir, "models", isVerbose)
+		if err != nil {
+			return fmt.Errorf("error finding model %s: %w", name, err)
+		}
+		path, found = core.FindSchemaFile(filepath.Dir(modelPath), name)
+	}
+	core.LogVerbose(isVerbose, "Using %s (documents %s already: %t)", path, name, found)
+
+	f, err := core.LoadSchemaFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	added := f.StubColumns(name, core.FlattenSchema(fields))
+
+	if yamlPrint {
+		data, err := f.Bytes()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	if added == 0 && found {
+		core.ColorPrint(core.Bold+core.Green, "✓ ")
+		fmt.Printf("%s already documents every column of %s\n", path, name)
+		return nil
+	}
+	if err := f.Save(); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+
+	core.ColorPrint(core.Bold+core.Green, "✓ ")
+	fmt.Printf("Added %d column(s) of %s to %s\n", added, name, path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(yamlCmd)
+
+	yamlCmd.Flags().BoolVarP(&yamlCompile, "compile", "c", false, "Compile the model first")
+	yamlCmd.Flags().BoolVar(&yamlNoCache, "no-cache", false, "Always dry run against BigQuery, ignoring cached results")
+	yamlCmd.Flags().BoolVar(&yamlPrint, "print", false, "Print the resulting YAML instead of writing it")
+	registerModelCompletion(yamlCmd, false)
+}
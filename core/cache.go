@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultCacheTTL = 24 * time.Hour
+
+// DryRunCache is an on-disk cache of successful dry-run results, keyed by the
+// SHA-256 of the compiled SQL plus the BigQuery project and location.
+// Each entry is a small JSON file so the cache can be inspected or deleted by hand.
+type DryRunCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+type cacheEntry struct {
+	BytesProcessed int64     `json:"bytes_processed"`
+	Out            string    `json:"out"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CacheStats summarises the contents of a DryRunCache
+type CacheStats struct {
+	Entries int
+	Expired int
+	Size    int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// NewDryRunCache returns the cache configured by cache-dir and cache-ttl,
+// defaulting to $XDG_CACHE_HOME/dibbity (or the OS equivalent) and 24h
+func NewDryRunCache() (*DryRunCache, error) {
+	dir := viper.GetString("cache-dir")
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, &ConfigError{Msg: "could not determine cache directory, set cache-dir", Err: err}
+		}
+		dir = filepath.Join(base, "dibbity", "dry-run")
+	}
+
+	ttl := viper.GetDuration("cache-ttl")
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	return &DryRunCache{Dir: dir, TTL: ttl}, nil
+}
+
+// CacheKey hashes the SQL together with the project and location it runs in,
+// and the API endpoint when it isn't Google's, so an emulator's results are kept apart
+func CacheKey(sql, project, location, api string) string {
+	h := sha256.New()
+	h.Write([]byte(project))
+	h.Write([]byte{0})
+	h.Write([]byte(location))
+	h.Write([]byte{0})
+	h.Write([]byte(sql))
+	if api != "" {
+		h.Write([]byte{0})
+		h.Write([]byte(api))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *DryRunCache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+".json")
+}
+
+// Get returns the cached entry for key, or false if missing or expired
+func (c *DryRunCache) Get(key string) (cacheEntry, bool) {
+	var e cacheEntry
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return e, false
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, false
+	}
+	if time.Since(e.CreatedAt) > c.TTL {
+		return e, false
+	}
+	return e, true
+}
+
+// Put stores an entry for key
+func (c *DryRunCache) Put(key string, e cacheEntry) error {
+	p := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// write then rename so a concurrent reader never sees half an entry
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// Stats walks the cache directory and counts entries
+func (c *DryRunCache) Stats() (CacheStats, error) {
+	var s CacheStats
+	err := filepath.WalkDir(c.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		s.Entries++
+		s.Size += info.Size()
+
+		mod := info.ModTime()
+		if time.Since(mod) > c.TTL {
+			s.Expired++
+		}
+		if s.Oldest.IsZero() || mod.Before(s.Oldest) {
+			s.Oldest = mod
+		}
+		if mod.After(s.Newest) {
+			s.Newest = mod
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	return s, err
+}
+
+// Clear removes every cached entry
+func (c *DryRunCache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// BqDryRunCached behaves like BqDryRun but serves unchanged SQL from cache.
+// Only successful dry runs are cached, so failures are always retried against BigQuery.
+// A nil cache disables caching.
+func (bq *BqRunner) BqDryRunCached(ctx context.Context, cache *DryRunCache, b bool) (*BqRunner, error) {
+	if cache == nil {
+		return bq.BqDryRun(ctx, b)
+	}
+
+	key := CacheKey(bq.Query, viper.GetString("bq-project"), viper.GetString("bq-location"), viper.GetString("bq-api"))
+	if e, ok := cache.Get(key); ok {
+		LogVerbose(b, "Cache hit for %s (cached %s ago)", key[:12], time.Since(e.CreatedAt).Round(time.Second))
+		bq.Out = e.Out
+		bq.BytesProcessed = e.BytesProcessed
+		bq.Ok = true
+		bq.Cached = true
+		return bq, nil
+	}
+
+	if _, err := bq.BqDryRun(ctx, b); err != nil {
+		return bq, err
+	}
+
+	if bq.Ok {
+		err := cache.Put(key, cacheEntry{BytesProcessed: bq.BytesProcessed, Out: bq.Out, CreatedAt: time.Now()})
+		if err != nil {
+			// a broken cache shouldn't fail the dry run
+			LogVerbose(b, "Could not write cache entry: %v", fmt.Errorf("%s: %w", cache.Dir, err))
+		}
+	}
+	return bq, nil
+}
@@ -0,0 +1,26 @@
+package core
+
+import "testing"
+
+func TestCacheKey(t *testing.T) {
+	base := CacheKey("select 1", "proj", "EU", "")
+	if len(base) != 64 {
+		t.Fatalf("CacheKey() = %q, want a sha256 hex digest", base)
+	}
+	if again := CacheKey("select 1", "proj", "EU", ""); again != base {
+		t.Fatalf("CacheKey() isn't stable: %q then %q", base, again)
+	}
+	differs := map[string]string{
+		"sql":      CacheKey("select 2", "proj", "EU", ""),
+		"project":  CacheKey("select 1", "other", "EU", ""),
+		"location": CacheKey("select 1", "proj", "US", ""),
+		"api":      CacheKey("select 1", "proj", "EU", "http://localhost:9050"),
+		// fields don't run into each other
+		"boundary": CacheKey("select 1", "pro", "jEU", ""),
+	}
+	for what, key := range differs {
+		if key == base {
+			t.Errorf("changing the %s gives the same key", what)
+		}
+	}
+}
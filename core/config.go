@@ -0,0 +1,252 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigKey documents a setting that can live in .dibbity.yaml
+type ConfigKey struct {
+	Key         string
+	Default     string // as written in yaml, empty for no default
+	Description string
+}
+
+// ConfigKeys lists every setting dibbity reads, in the order `dibbity init`
+// writes them. Each has a flag of the same name and a DIBBITY_ environment
+// variable. Keep it in sync when adding flags or viper lookups.
+var ConfigKeys = []ConfigKey{
+	{"project", "", "Named project from `projects:` to use by default"},
+	{"dbt-dir", "", "Path to the dbt project (the folder containing dbt_project.yml)"},
+	{"runner", "poetry", "How to run dbt: poetry, uv, pipenv or none (dbt on $PATH)"},
+	{"verbose", "false", "Verbose output"},
+	{"picker", "true", "Open the interactive model picker when no models are selected"},
+	{"bq-project", "", "GCP project bq runs dry runs in"},
+	{"bq-location", "", "BigQuery location, e.g. EU or US"},
+	{"bq-api", "", "BigQuery API endpoint bq talks to, e.g. a local emulator (empty for Google's)"},
+	{"bq-url-template", DefaultBqURLTemplate, "URL `open` uses, with {project}, {dataset} and {table} placeholders"},
+	{"price-per-tib", "0", "On-demand price per TiB scanned, to estimate dry run costs (0 to hide)"},
+	{"state-path", "target_prod", "Production artefacts used with --defer"},
+	{"lookml-dir", "", "Path to the LookML project the `lookml` commands read views from"},
+	{"max-bytes", "", "Fail a dry run if total data to process exceeds this size (e.g. 500MB, 2TB)"},
+	{"confirm-bytes", "1GB", "Ask before `pull` scans more than this much data"},
+	{"timeout", "0s", "Timeout for the whole command (0s for none)"},
+	{"dbt-timeout", "0s", "Timeout for each dbt invocation"},
+	{"bq-timeout", "0s", "Timeout for each bq invocation"},
+	{"bq-retries", "3", "Retries for transient bq failures"},
+	{"bq-retry-backoff", "1s", "Initial backoff between bq retries"},
+	{"bq-retry-max-backoff", "30s", "Maximum backoff between bq retries"},
+	{"cache-dir", "", "Dry run cache location (default: user cache dir)"},
+	{"cache-ttl", "24h", "How long cached dry run results are valid"},
+}
+
+// DefaultBqURLTemplate opens a table in BigQuery Studio
+const DefaultBqURLTemplate = "https://console.cloud.google.com/bigquery?p={project}&d={dataset}&t={table}&page=table"
+
+// IsConfigKey reports whether key is a known setting. Keys inside a named
+// project ("projects.<name>.<key>") are checked against the same list.
+func IsConfigKey(key string) bool {
+	if rest, ok := strings.CutPrefix(key, "projects."); ok {
+		_, key, ok = strings.Cut(rest, ".")
+		if !ok || key == "project" {
+			return false
+		}
+	}
+	for _, k := range ConfigKeys {
+		if k.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Runners maps each supported runner to the prefix used to invoke a program through it
+var Runners = map[string][]string{
+	"poetry": {"poetry", "run"},
+	"uv":     {"uv", "run"},
+	"pipenv": {"pipenv", "run"},
+	"none":   {},
+}
+
+// DetectRunner guesses the python runner from the lock files in dir,
+// falling back to "none" when dbt is installed globally
+func DetectRunner(dir string) string {
+	for _, c := range []struct{ file, runner string }{
+		{"poetry.lock", "poetry"},
+		{"uv.lock", "uv"},
+		{"Pipfile.lock", "pipenv"},
+		{"Pipfile", "pipenv"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, c.file)); err == nil {
+			return c.runner
+		}
+	}
+	if _, err := exec.LookPath("dbt"); err == nil {
+		return "none"
+	}
+	return "poetry"
+}
+
+// FindProjectRoot walks up from dir looking for dbt_project.yml, like git
+// looks for .git. It returns the directory containing it.
+func FindProjectRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "dbt_project.yml")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// DbtProject is the part of dbt_project.yml we care about
+type DbtProject struct {
+	Name    string `yaml:"name"`
+	Profile string `yaml:"profile"`
+}
+
+// LoadDbtProject parses dbt_project.yml in dir
+func LoadDbtProject(dir string) (*DbtProject, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "dbt_project.yml"))
+	if err != nil {
+		return nil, err
+	}
+	var p DbtProject
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse dbt_project.yml: %w", err)
+	}
+	return &p, nil
+}
+
+// ProfileTarget is the BigQuery connection of the default target in profiles.yml
+type ProfileTarget struct {
+	Name     string
+	Project  string
+	Location string
+	Path     string // profiles.yml it came from
+}
+
+// LoadProfileTarget finds profiles.yml the way dbt does ($DBT_PROFILES_DIR,
+// the project dir, then ~/.dbt) and returns the default target of profile
+func LoadProfileTarget(dbtDir string, profile string) (*ProfileTarget, error) {
+	var dirs []string
+	if d := os.Getenv("DBT_PROFILES_DIR"); d != "" {
+		dirs = append(dirs, d)
+	}
+	dirs = append(dirs, dbtDir)
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".dbt"))
+	}
+
+	for _, d := range dirs {
+		p := filepath.Join(d, "profiles.yml")
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+
+		var profiles map[string]struct {
+			Target  string `yaml:"target"`
+			Outputs map[string]struct {
+				Project  string `yaml:"project"`
+				Location string `yaml:"location"`
+			} `yaml:"outputs"`
+		}
+		if err := yaml.Unmarshal(data, &profiles); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+		}
+
+		prof, ok := profiles[profile]
+		if !ok {
+			continue
+		}
+		out := prof.Outputs[prof.Target]
+		return &ProfileTarget{Name: prof.Target, Project: out.Project, Location: out.Location, Path: p}, nil
+	}
+	return nil, &NotFoundError{Kind: "profile", Name: profile}
+}
+
+// HasGcloudCredentials reports where application default credentials were
+// found, or "" if there are none
+func HasGcloudCredentials() string {
+	if p := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); p != "" {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	configDir := os.Getenv("CLOUDSDK_CONFIG")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(home, ".config", "gcloud")
+	}
+	for _, f := range []string{"application_default_credentials.json", "credentials.db"} {
+		p := filepath.Join(configDir, f)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// WriteConfigTemplate writes a commented .dibbity.yaml to w. values override
+// the defaults in ConfigKeys; keys without a value are written commented out.
+func WriteConfigTemplate(w io.Writer, values map[string]string, header string) error {
+	for _, line := range strings.Split(header, "\n") {
+		fmt.Fprintf(w, "# %s\n", line)
+	}
+	fmt.Fprintln(w)
+
+	for _, k := range ConfigKeys {
+		fmt.Fprintf(w, "# %s\n", k.Description)
+		v, ok := values[k.Key]
+		if !ok {
+			v = k.Default
+		}
+
+		node := yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		quoted, err := yaml.Marshal(&node)
+		if err != nil {
+			return err
+		}
+		entry := fmt.Sprintf("%s: %s", k.Key, strings.TrimSpace(string(quoted)))
+
+		switch {
+		case v == "":
+			entry = "# " + k.Key + ":"
+		case !ok:
+			entry = "# " + entry
+		}
+		fmt.Fprintln(w, entry)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprint(w, `# Named projects override any of the keys above while active.
+# Pick one with --project <name>, the project key, or by running inside its dbt-dir.
+# projects:
+#   warehouse:
+#     dbt-dir: ~/code/warehouse
+#     bq-project: my-warehouse-project
+#   marketing:
+#     dbt-dir: ~/code/marketing-dbt
+#     runner: uv
+#     bq-location: EU
+`)
+	return nil
+}
@@ -2,17 +2,19 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/spf13/viper"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Color constants for terminal output
@@ -90,6 +92,9 @@ var BoxChars = [][]string{
 	{"+", "-", "+", "|", "+", "+"},                               // Simple
 }
 
+// boxTees are the left tee of each box type, used to draw trees
+var boxTees = []string{"\u251C", "\u2560", "\u251C", "\u2523", "+"}
+
 // FormatBytes formats bytes into human-readable format with appropriate units
 func FormatBytes(bytes int64) string {
 	const unit = 1024
@@ -119,12 +124,55 @@ func FormatBytes(bytes int64) string {
 	return fmt.Sprintf("%s%.2f %ciB%s", color, float64(bytes)/float64(div), "KMGTPE"[exp], Reset)
 }
 
+// FormatPrice estimates the on-demand cost of scanning bytes at pricePerTiB dollars per TiB
+func FormatPrice(bytes int64, pricePerTiB float64) string {
+	return fmt.Sprintf("$%.2f", float64(bytes)/(1<<40)*pricePerTiB)
+}
+
+// ParseBytes parses a human-readable size such as "500MB", "10GiB" or "1024"
+// into bytes. Decimal (KB, MB...) and binary (KiB, MiB...) suffixes are both
+// treated as powers of 1024, matching FormatBytes. Negative and malformed
+// sizes are a ConfigError, so a typo can't switch a budget off.
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	m := sizeRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, &ConfigError{Msg: fmt.Sprintf("invalid size %q, expected e.g. 500MB or 2TiB", s)}
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, &ConfigError{Msg: fmt.Sprintf("invalid size %q", s), Err: err}
+	}
+
+	mult := 1.0
+	if m[2] != "" {
+		for range strings.Index("KMGTPE", m[2]) + 1 {
+			mult *= 1024
+		}
+	}
+	if n*mult >= math.MaxInt64 {
+		return 0, &ConfigError{Msg: fmt.Sprintf("size %q is too large", s)}
+	}
+	return int64(n * mult), nil
+}
+
+// sizeRegex matches an unsigned number with an optional unit, e.g. 1.5GiB
+var sizeRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([KMGTPE]?)(I?B)?$`)
+
 type DbtOptions struct {
 	Command string   // the command to actually run
 	Select  []string // the models we want to run
 	Empty   bool
 	Defer   bool
 	Compile bool // do we want to first compile the models?
+
+	FullRefresh bool   // compile/run as if --full-refresh, so is_incremental() is false
+	TargetPath  string // compile into this target dir instead of target/
+	StatePath   string // production artefacts used with Defer, defaults to target_prod
 }
 
 func (opts *DbtOptions) BuildArgs() []string {
@@ -135,13 +183,25 @@ func (opts *DbtOptions) BuildArgs() []string {
 	}
 
 	if opts.Defer {
-		args = append(args, []string{"--defer", "--state", "target_prod", "--favor-state"}...)
+		state := opts.StatePath
+		if state == "" {
+			state = "target_prod"
+		}
+		args = append(args, []string{"--defer", "--state", state, "--favor-state"}...)
 	}
 
 	if opts.Empty {
 		args = append(args, "--empty")
 	}
 
+	if opts.FullRefresh {
+		args = append(args, "--full-refresh")
+	}
+
+	if opts.TargetPath != "" {
+		args = append(args, "--target-path", opts.TargetPath)
+	}
+
 	return args
 }
 
@@ -151,6 +211,9 @@ type BqRunner struct {
 	BytesProcessed int64
 	Ok             bool
 	RespError      string
+	Attempts       int      // number of bq invocations, including retries
+	Retries        []string // reason for each retried attempt
+	Cached         bool     // result was served from the DryRunCache
 	// TODO: also check the docs for other things to add
 }
 
@@ -184,11 +247,12 @@ func (r *BqDryRunResponse) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-func (bq *BqRunner) BqDryRun(b bool) (*BqRunner, error) {
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-
-	args := []string{"query", "--nouse_legacy_sql", "--dry_run", "--nouse_cache", "--format=json"}
+// BqDryRun dry runs bq.Query, bounded by ctx and the --bq-timeout setting.
+// Transient failures (rate limits, backend errors, network blips) are retried
+// with exponential backoff according to RetryPolicyFromConfig.
+func (bq *BqRunner) BqDryRun(ctx context.Context, b bool) (*BqRunner, error) {
+	args := bqGlobalArgs()
+	args = append(args, "query", "--nouse_legacy_sql", "--dry_run", "--nouse_cache", "--format=json")
 
 	// Print a fancy command execution message
 	if b {
@@ -198,21 +262,47 @@ func (bq *BqRunner) BqDryRun(b bool) (*BqRunner, error) {
 		ColorPrintln(BrightYellow, cmdStr)
 		ColorPrintln(Dim, "  Query being passed via stdin...")
 	}
-	c := exec.Command("bq", args...)
-
-	c.Stdin = strings.NewReader(bq.Query) // piping in with stdin to ensure that queries beginning with `--` comment are interpreted as single arguments, not as an extra flag
-	c.Stdout = &out
-	c.Stderr = &stderr
 
 	if b {
 		ColorPrint(Blue, "⧗ ")
 		ColorPrintln(Blue, "Running query analysis...")
 	}
 
-	err := c.Run()
+	policy := RetryPolicyFromConfig()
+	bq.Attempts = 0
+	bq.Retries = nil
+
+	var out, stderr string
+	var err error
+	for {
+		bq.Attempts++
+		out, stderr, err = runBq(ctx, args, bq.Query)
+		if err == nil {
+			break
+		}
+		// never retry a timeout or interrupt
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return bq, err
+		}
+
+		reason, transient := ClassifyBqError(out + "\n" + stderr)
+		if !transient || bq.Attempts >= policy.MaxAttempts {
+			break
+		}
+
+		wait := policy.Backoff(bq.Attempts)
+		bq.Retries = append(bq.Retries, reason)
+		LogVerbose(b, "Transient bq error (%s), retrying in %s (attempt %d/%d)", reason, wait.Round(time.Millisecond), bq.Attempts+1, policy.MaxAttempts)
+
+		if err := Sleep(ctx, wait); err != nil {
+			return bq, err
+		}
+	}
+
 	if err != nil {
 		bq.Ok = false
-		bq.RespError = out.String()
+		bq.RespError = out
 
 		if b {
 			ColorPrintln(Bold+BgRed+White, " ERROR ")
@@ -222,17 +312,17 @@ func (bq *BqRunner) BqDryRun(b bool) (*BqRunner, error) {
 		return bq, nil // return without error so the caller can check bq.Ok
 	}
 
-	bq.Out = out.String()
+	bq.Out = out
 	bq.Ok = true
 
 	var stats BqDryRunResponse
-	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+	if err := json.Unmarshal([]byte(out), &stats); err != nil {
 
 		if b {
 			ColorPrintln(Bold+BgRed+White, " ERROR ")
 			PrintBox("Failed to parse response", err.Error(), BoxRounded, Red)
 		}
-		return &BqRunner{}, fmt.Errorf("failed to unmarshal bq dry run response: %w", err)
+		return &BqRunner{}, &BqError{Msg: "failed to unmarshal bq dry run response", Err: err}
 	}
 	bq.BytesProcessed = stats.TotalBytesProcessed
 
@@ -247,6 +337,44 @@ func (bq *BqRunner) BqDryRun(b bool) (*BqRunner, error) {
 	return bq, nil
 }
 
+// bqGlobalArgs returns the bq global flags for the configured bq-project and bq-location
+func bqGlobalArgs() []string {
+	var args []string
+	if p := viper.GetString("bq-project"); p != "" {
+		args = append(args, "--project_id="+p)
+	}
+	if l := viper.GetString("bq-location"); l != "" {
+		args = append(args, "--location="+l)
+	}
+	if api := viper.GetString("bq-api"); api != "" {
+		args = append(args, "--api="+api)
+	}
+	return args
+}
+
+// runBq runs a single bq invocation with the query on stdin, bounded by --bq-timeout
+func runBq(ctx context.Context, args []string, query string) (string, string, error) {
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+
+	ctx, cancel := WithTimeout(ctx, StepBq, StepTimeout(StepBq))
+	defer cancel()
+
+	c := newCommand(ctx, "bq", args...)
+
+	c.Stdin = strings.NewReader(query) // piping in with stdin to ensure that queries beginning with `--` comment are interpreted as single arguments, not as an extra flag
+	c.Stdout = &out
+	c.Stderr = &stderr
+
+	err := c.Run()
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return out.String(), stderr.String(), ctxErr
+		}
+	}
+	return out.String(), stderr.String(), err
+}
+
 func LogVerbose(b bool, format string, a ...interface{}) {
 	if !b {
 		return
@@ -262,25 +390,37 @@ func LogVerbose(b bool, format string, a ...interface{}) {
 	fmt.Println(message)
 }
 
-// GetFolder retrieves the directory path specified by the "dbt-dir" configuration key, resolving "~" to the user's home directory.
+// GetFolder returns the dbt project to work in from the "dbt-dir" key,
+// resolving "~" to the user's home directory. Running inside a dbt project sets
+// dbt-dir to it (see FindProjectRoot), above config files but below
+// --dbt-dir, DIBBITY_DBT_DIR and an explicit --project.
 func GetFolder(b bool) (string, error) {
 	dbtDir := viper.GetString("dbt-dir")
 
-	LogVerbose(b, "Using dbt folder: %s", dbtDir)
+	if p := ActiveProject(); p != "" {
+		LogVerbose(b, "Using dbt folder of project %s: %s", p, dbtDir)
+	} else {
+		LogVerbose(b, "Using dbt folder: %s", dbtDir)
+	}
 
-	if strings.HasPrefix(dbtDir, "~/") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", fmt.Errorf("failed to get user home directory: %w", err)
-		}
-		dbtDir = strings.Replace(dbtDir, "~", home, 1)
+	if dbtDir == "" {
+		return "", &ConfigError{Msg: "dbt-dir is not set"}
+	}
+
+	dbtDir, err := ExpandHome(dbtDir)
+	if err != nil {
+		return "", &ConfigError{Msg: "failed to get user home directory", Err: err}
+	}
+
+	if info, err := os.Stat(dbtDir); err != nil || !info.IsDir() {
+		return "", &ConfigError{Msg: fmt.Sprintf("dbt-dir %q is not a directory", dbtDir), Err: err}
 	}
 	return dbtDir, nil
 }
 
 // ListDir runs `ls -l` in directory path specified by the "dbt-dir" configuration key
-func ListDir(dir string, b bool) error {
-	c := exec.Command("ls", "-l")
+func ListDir(ctx context.Context, dir string, b bool) error {
+	c := newCommand(ctx, "ls", "-l")
 
 	c.Dir = dir // change the directory
 	c.Stdout = os.Stdout
@@ -290,15 +430,29 @@ func ListDir(dir string, b bool) error {
 	return err
 }
 
-// PoetryRun can run arbitrary commands in the directory path specified by the "dbt-dir" configuration key
-func PoetryRun(program string, args []string, dir string, b bool) (string, error) {
-	cmdArgs := []string{"run", program}
+// PoetryRun can run arbitrary commands in the directory path specified by the "dbt-dir" configuration key.
+// Despite the name it goes through the configured "runner" (poetry, uv, pipenv or none).
+// The command is killed when ctx is cancelled or the --dbt-timeout elapses.
+func PoetryRun(ctx context.Context, program string, args []string, dir string, b bool) (string, error) {
+	runner := viper.GetString("runner")
+	if runner == "" {
+		runner = "poetry"
+	}
+	prefix, ok := Runners[runner]
+	if !ok {
+		return "", &ConfigError{Msg: fmt.Sprintf("unknown runner %q, expected poetry, uv, pipenv or none", runner)}
+	}
+
+	cmdArgs := append(append([]string{}, prefix...), program)
 	cmdArgs = append(cmdArgs, args...)
 
-	LogVerbose(b, "Running: poetry %s", strings.Join(cmdArgs, " "))
+	LogVerbose(b, "Running: %s", strings.Join(cmdArgs, " "))
+
+	ctx, cancel := WithTimeout(ctx, StepDbt, StepTimeout(StepDbt))
+	defer cancel()
 
 	//c := exec.Command("zsh", "-c", a)
-	c := exec.Command("poetry", cmdArgs...)
+	c := newCommand(ctx, cmdArgs[0], cmdArgs[1:]...)
 
 	c.Dir = dir
 
@@ -315,7 +469,10 @@ func PoetryRun(program string, args []string, dir string, b bool) (string, error
 
 	err := c.Run()
 	if err != nil {
-		return errBuf.String(), err
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return errBuf.String(), ctxErr
+		}
+		return errBuf.String(), &DbtError{Args: cmdArgs, Stderr: errBuf.String(), Err: err}
 	}
 	return outBuf.String(), nil
 }
@@ -350,7 +507,7 @@ func unmarshalNames(s string) ([]string, error) {
 	return names, nil
 }
 
-func ListModels(m []string, dir string, b bool) ([]string, error) {
+func ListModels(ctx context.Context, m []string, dir string, b bool) ([]string, error) {
 
 	opts := DbtOptions{
 		Command: "ls",
@@ -360,7 +517,7 @@ func ListModels(m []string, dir string, b bool) ([]string, error) {
 	args := opts.BuildArgs()
 
 	args = append(args, "--resource-type", "model", "--output", "json", "--output-keys", "name", "--quiet")
-	s, err := PoetryRun("dbt", args, dir, b)
+	s, err := PoetryRun(ctx, "dbt", args, dir, b)
 
 	if err != nil {
 		return nil, err
@@ -374,12 +531,12 @@ func ListModels(m []string, dir string, b bool) ([]string, error) {
 }
 
 // CompileModel compiles the dbt models set in DbtOptions.Select
-func CompileModel(opts DbtOptions, dir string, b bool) error {
+func CompileModel(ctx context.Context, opts DbtOptions, dir string, b bool) error {
 
 	opts.Command = "compile"
 	args := opts.BuildArgs()
 
-	_, err := PoetryRun("dbt", args, dir, b)
+	_, err := PoetryRun(ctx, "dbt", args, dir, b)
 	if err != nil {
 		return err
 	}
@@ -422,11 +579,14 @@ func FindFilepath(modelName string, dir string, subDir string, b bool) (string,
 	})
 
 	if walkErr != nil && !errors.Is(walkErr, errFound) {
+		if errors.Is(walkErr, os.ErrNotExist) {
+			return "", &NotFoundError{Kind: "directory", Name: dir}
+		}
 		return "", fmt.Errorf("error walking the path %q: %w", dir, walkErr)
 	}
 
 	if filePath == "" {
-		return "", fmt.Errorf("model '%s' not found", modelName)
+		return "", &NotFoundError{Kind: "model", Name: modelName}
 	}
 
 	LogVerbose(b, "Found model %s at %s", modelName, filePath)
@@ -446,6 +606,33 @@ func VisualLength(str string) int {
 	return len(StripANSI(str))
 }
 
+// TreeNode is a line in a tree drawn by RenderTree
+type TreeNode struct {
+	Label    string
+	Children []*TreeNode
+}
+
+// RenderTree draws a tree with the branch characters of a box type
+func RenderTree(root *TreeNode, boxType int, colors string) string {
+	chars := BoxChars[boxType]
+	var sb strings.Builder
+	sb.WriteString(root.Label + "\n")
+
+	var walk func(n *TreeNode, prefix string)
+	walk = func(n *TreeNode, prefix string) {
+		for i, c := range n.Children {
+			branch, indent := boxTees[boxType], chars[3]+"  "
+			if i == len(n.Children)-1 {
+				branch, indent = chars[4], "   "
+			}
+			sb.WriteString(colors + prefix + branch + chars[1] + " " + Reset + c.Label + "\n")
+			walk(c, prefix+indent)
+		}
+	}
+	walk(root, "")
+	return sb.String()
+}
+
 // PrintBox prints a box with title and content
 func PrintBox(title string, content string, boxType int, colors string) {
 	chars := BoxChars[boxType]
@@ -496,3 +683,36 @@ func PrintBox(title string, content string, boxType int, colors string) {
 	ColorPrint(colors, strings.Repeat(chars[1], width)) // Bottom border
 	ColorPrintln(colors, chars[5])                      // Bottom right corner
 }
+
+// PrintTable prints rows as left-aligned columns under a bold header. Cells
+// may contain ANSI colours; long cells are truncated to maxWidth (0 for no limit).
+func PrintTable(headers []string, rows [][]string, maxWidth int) {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i := range row {
+			if maxWidth > 0 {
+				row[i] = truncateVisual(row[i], maxWidth)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(StripANSI(row[i])))
+		}
+	}
+
+	line := func(cells []string) string {
+		var sb strings.Builder
+		for i, c := range cells {
+			sb.WriteString(c)
+			if i < len(cells)-1 {
+				sb.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(StripANSI(c))+2))
+			}
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+
+	ColorPrintln(Bold, line(headers))
+	for _, row := range rows {
+		fmt.Println(line(row))
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"1024", 1024, false},
+		{"0", 0, false},
+		{"500MB", 500 << 20, false},
+		{"500 mb", 500 << 20, false},
+		{"10GiB", 10 << 30, false},
+		{"1.5K", 1536, false},
+		{"2T", 2 << 40, false},
+		{"7B", 7, false},
+		{"-1GB", 0, true},
+		{"-5", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"1e3", 0, true},
+		{"0x10", 0, true},
+		{"5I", 0, true},
+		{"GB", 0, true},
+		{"5XB", 0, true},
+		{"1.2.3MB", 0, true},
+		{"99999999999EB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBytes(tt.in)
+			if tt.wantErr {
+				var cfgErr *ConfigError
+				if !errors.As(err, &cfgErr) {
+					t.Fatalf("ParseBytes(%q) = %d, %v, want a ConfigError", tt.in, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseBytes(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
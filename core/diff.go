@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnifiedDiff returns a coloured unified diff of two versions of a file, with
+// three lines of context, or "" if they're the same
+func UnifiedDiff(path string, before, after string) string {
+	if before == after {
+		return ""
+	}
+	a := splitLines(before)
+	b := splitLines(after)
+
+	// edits are usually small, so only diff what's between the common prefix and suffix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// longest common subsequence of the changed middle
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		line string
+		i, j int // line numbers before / after, 0-based
+	}
+	var ops []op
+	for k := range prefix {
+		ops = append(ops, op{' ', a[k], k, k})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, op{' ', ma[i], prefix + i, prefix + j})
+			i, j = i+1, j+1
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', ma[i], prefix + i, prefix + j})
+			i++
+		default:
+			ops = append(ops, op{'+', mb[j], prefix + i, prefix + j})
+			j++
+		}
+	}
+	for k := range suffix {
+		ops = append(ops, op{' ', a[len(a)-suffix+k], len(a) - suffix + k, len(b) - suffix + k})
+	}
+
+	const context = 3
+	var sb strings.Builder
+	sb.WriteString(Bold + "--- a/" + path + "\n+++ b/" + path + Reset + "\n")
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		// grow the hunk while changes are within 2*context lines of each other
+		start := max(k-context, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*context {
+				break
+			}
+			end = next
+		}
+		end = min(end+context, len(ops))
+
+		oldCount, newCount := 0, 0
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				oldCount++
+			}
+			if o.kind != '-' {
+				newCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("%s@@ -%d,%d +%d,%d @@%s\n", Cyan, ops[start].i+1, oldCount, ops[start].j+1, newCount, Reset))
+		for _, o := range ops[start:end] {
+			line := strings.TrimSuffix(o.line, "\n")
+			switch o.kind {
+			case '+':
+				sb.WriteString(Green + "+" + line + Reset + "\n")
+			case '-':
+				sb.WriteString(Red + "-" + line + Reset + "\n")
+			default:
+				sb.WriteString(" " + line + "\n")
+			}
+		}
+		k = end
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
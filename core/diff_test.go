@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{
+			"changed line",
+			"a\nb\nc\n",
+			"a\nB\nc\n",
+			"--- a/f.yml\n+++ b/f.yml\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			"added at the end",
+			"1\n2\n3\n4\n5\n",
+			"1\n2\n3\n4\n5\n6\n",
+			"--- a/f.yml\n+++ b/f.yml\n@@ -3,3 +3,4 @@\n 3\n 4\n 5\n+6\n",
+		},
+		{
+			"two hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			"--- a/f.yml\n+++ b/f.yml\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+		{
+			"nearby changes share a hunk",
+			"1\n2\n3\n4\n5\n6\n",
+			"x\n2\n3\n4\n5\ny\n",
+			"--- a/f.yml\n+++ b/f.yml\n@@ -1,6 +1,6 @@\n-1\n+x\n 2\n 3\n 4\n 5\n-6\n+y\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(UnifiedDiff("f.yml", tt.before, tt.after)); got != tt.want {
+				t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffLargeFile(t *testing.T) {
+	var before, after strings.Builder
+	for i := range 50000 {
+		line := fmt.Sprintf("line %d\n", i)
+		before.WriteString(line)
+		if i == 25000 {
+			line = "changed\n"
+		}
+		after.WriteString(line)
+	}
+	want := "--- a/f.yml\n+++ b/f.yml\n@@ -24998,7 +24998,7 @@\n line 24997\n line 24998\n line 24999\n-line 25000\n+changed\n line 25001\n line 25002\n line 25003\n"
+	if got := StripANSI(UnifiedDiff("f.yml", before.String(), after.String())); got != want {
+		t.Fatalf("UnifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
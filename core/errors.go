@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exit codes returned by dibbity. These are part of the CLI contract so that
+// scripts and CI can react to specific failure classes; do not renumber them.
+const (
+	ExitOK             = 0   // everything worked
+	ExitError          = 1   // unclassified error, including bad flags / usage
+	ExitConfig         = 2   // invalid or missing configuration
+	ExitDbt            = 3   // dbt (or the python runner) failed
+	ExitBq             = 4   // bq failed, or one or more models failed to dry run
+	ExitNotFound       = 5   // a model or file could not be found
+	ExitBudgetExceeded = 6   // the dry run exceeded the configured byte budget
+	ExitTimeout        = 7   // a step or the whole command ran past its timeout
+	ExitSchemaDrift    = 8   // documented columns or LookML fields don't match the models' output
+	ExitInterrupted    = 130 // cancelled by SIGINT / SIGTERM, as a shell would report
+)
+
+// ExitCoder is implemented by every error in the dibbity error hierarchy
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitCode maps an error to the process exit code. Wrapped errors are unwrapped
+// with errors.As, so the first typed error in the chain decides the code.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		return ec.ExitCode()
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+	return ExitError
+}
+
+// ConfigError reports a problem with the dibbity configuration
+type ConfigError struct {
+	Msg string
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("config error: %s: %v", e.Msg, e.Err)
+	}
+	return "config error: " + e.Msg
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+func (e *ConfigError) ExitCode() int { return ExitConfig }
+
+// DbtError reports a failed dbt invocation, keeping the captured stderr
+type DbtError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *DbtError) Error() string {
+	msg := fmt.Sprintf("`%s` failed: %v", strings.Join(e.Args, " "), e.Err)
+	if s := strings.TrimSpace(e.Stderr); s != "" {
+		msg += "\n" + s
+	}
+	return msg
+}
+
+func (e *DbtError) Unwrap() error { return e.Err }
+func (e *DbtError) ExitCode() int { return ExitDbt }
+
+// BqError reports a failed bq invocation or failed model dry runs
+type BqError struct {
+	Msg string
+	Err error
+}
+
+func (e *BqError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("bq error: %s: %v", e.Msg, e.Err)
+	}
+	return "bq error: " + e.Msg
+}
+
+func (e *BqError) Unwrap() error { return e.Err }
+func (e *BqError) ExitCode() int { return ExitBq }
+
+// NotFoundError reports that a model, file or directory does not exist
+type NotFoundError struct {
+	Kind string // e.g. "model", "file"
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s '%s' not found", e.Kind, e.Name)
+}
+
+func (e *NotFoundError) ExitCode() int { return ExitNotFound }
+
+// BudgetExceededError reports that the bytes to be processed exceed the budget
+type BudgetExceededError struct {
+	Limit  int64
+	Actual int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded: %s to process, limit is %s",
+		StripANSI(FormatBytes(e.Actual)), StripANSI(FormatBytes(e.Limit)))
+}
+
+func (e *BudgetExceededError) ExitCode() int { return ExitBudgetExceeded }
+
+// TimeoutError reports that a step did not finish within its timeout
+type TimeoutError struct {
+	Step  string // "dbt", "bq" or "command" for the global --timeout
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Step, e.After)
+}
+
+func (e *TimeoutError) ExitCode() int { return ExitTimeout }
+
+// SchemaDriftError reports that documented columns differ from what models output
+type SchemaDriftError struct {
+	Models   int // models with drift
+	Problems int // missing, extra and mismatched columns
+}
+
+func (e *SchemaDriftError) Error() string {
+	return fmt.Sprintf("schema drift: %d problem(s) in %d model(s)", e.Problems, e.Models)
+}
+
+func (e *SchemaDriftError) ExitCode() int { return ExitSchemaDrift }
+
+// LookMLDriftError reports that a LookML view references columns its model no longer has
+type LookMLDriftError struct {
+	View    string
+	Model   string
+	Missing int // columns referenced by the view but not in the model
+}
+
+func (e *LookMLDriftError) Error() string {
+	return fmt.Sprintf("LookML drift: view %s references %d column(s) missing from model %s", e.View, e.Missing, e.Model)
+}
+
+func (e *LookMLDriftError) ExitCode() int { return ExitSchemaDrift }
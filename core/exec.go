@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Steps that can be given their own timeout with --<step>-timeout
+const (
+	StepDbt = "dbt"
+	StepBq  = "bq"
+)
+
+// StepTimeout returns the timeout configured for a step, e.g. "dbt-timeout".
+// Zero means the step is only bounded by the global --timeout (if any).
+func StepTimeout(step string) time.Duration {
+	return viper.GetDuration(step + "-timeout")
+}
+
+// WithTimeout bounds ctx by d, recording a TimeoutError as the cancellation
+// cause so callers can report which step ran out of time. A zero d returns
+// ctx unchanged.
+func WithTimeout(ctx context.Context, step string, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, d, &TimeoutError{Step: step, After: d})
+}
+
+// newCommand builds a command bound to ctx. The child runs in its own process
+// group so that cancelling ctx (timeout, SIGINT, SIGTERM) kills the whole tree,
+// e.g. poetry and the dbt process it spawned.
+func newCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	c := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(c)
+	c.WaitDelay = 5 * time.Second // don't hang on grandchildren holding our pipes
+	return c
+}
+
+// contextErr returns the reason ctx was cancelled, or nil if it is still live.
+// Use it after a failed command to tell a timeout or interrupt from a real failure.
+func contextErr(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit that touched one or more of a model's files
+type Commit struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Subject string
+	Files   []string
+}
+
+// BlameLine is a line of a file with the commit that last changed it
+type BlameLine struct {
+	Hash    string
+	Author  string
+	Time    time.Time
+	Summary string
+	Line    int
+	Text    string
+}
+
+// runGit runs git in dir and returns its stdout
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	var out, stderr bytes.Buffer
+	c := newCommand(ctx, "git", args...)
+	c.Dir = dir
+	c.Stdout = &out
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", fmt.Errorf("git %s failed: %s: %w", args[0], strings.TrimSpace(stderr.String()), err)
+	}
+	return out.String(), nil
+}
+
+// GitLog returns the last limit commits touching each of paths, following
+// renames, newest first. A commit touching several paths is listed once.
+func GitLog(ctx context.Context, dir string, paths []string, limit int) ([]Commit, error) {
+	byHash := map[string]*Commit{}
+	for _, path := range paths {
+		args := []string{"log", "--follow", "--format=%H%x1f%an%x1f%at%x1f%s"}
+		if limit > 0 {
+			args = append(args, "-n", strconv.Itoa(limit))
+		}
+		out, err := runGit(ctx, dir, append(args, "--", path)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			parts := strings.SplitN(line, "\x1f", 4)
+			if len(parts) != 4 {
+				continue
+			}
+			c, ok := byHash[parts[0]]
+			if !ok {
+				ts, _ := strconv.ParseInt(parts[2], 10, 64)
+				c = &Commit{Hash: parts[0], Author: parts[1], Time: time.Unix(ts, 0), Subject: parts[3]}
+				byHash[parts[0]] = c
+			}
+			c.Files = append(c.Files, path)
+		}
+	}
+
+	commits := make([]Commit, 0, len(byHash))
+	for _, c := range byHash {
+		commits = append(commits, *c)
+	}
+	sort.Slice(commits, func(i, j int) bool { return commits[i].Time.After(commits[j].Time) })
+	if limit > 0 && len(commits) > limit {
+		commits = commits[:limit]
+	}
+	return commits, nil
+}
+
+// GitLogPatch returns `git log -p` of the last limit commits touching path,
+// coloured by git
+func GitLogPatch(ctx context.Context, dir string, path string, limit int) (string, error) {
+	args := []string{"log", "--follow", "-p", "--color=always", "--format=%C(yellow)%h%Creset %s %C(dim)(%an, %ad)%Creset", "--date=short"}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	return runGit(ctx, dir, append(args, "--", path)...)
+}
+
+// GitBlame returns every line of path with the commit that last changed it
+func GitBlame(ctx context.Context, dir string, path string) ([]BlameLine, error) {
+	out, err := runGit(ctx, dir, "blame", "--line-porcelain", "--", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []BlameLine
+	var cur BlameLine
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			cur.Text = line[1:]
+			lines = append(lines, cur)
+			cur = BlameLine{}
+		case strings.HasPrefix(line, "author "):
+			cur.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			ts, _ := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			cur.Time = time.Unix(ts, 0)
+		case strings.HasPrefix(line, "summary "):
+			cur.Summary = strings.TrimPrefix(line, "summary ")
+		default:
+			// "<hash> <original line> <final line> [<lines in group>]" starts each entry
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && len(fields[0]) == 40 && cur.Hash == "" {
+				cur.Hash = fields[0]
+				cur.Line, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,337 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LineageGraph is a slice of the dbt DAG around some selected nodes
+type LineageGraph struct {
+	Nodes []*LineageNode
+	Edges []LineageEdge
+	byID  map[string]*LineageNode
+}
+
+// LineageNode is a node in a LineageGraph. Depth is negative upstream of the
+// selection and positive downstream of it.
+type LineageNode struct {
+	Node     *Node
+	Depth    int
+	Selected bool
+	Bytes    int64 // dry run bytes, -1 if not dry run
+}
+
+// LineageEdge points from a parent to a child
+type LineageEdge struct {
+	From, To string
+}
+
+// Lineage walks up to `up` levels of parents and `down` levels of children from
+// the selected nodes. A negative depth means no limit.
+func (m *Manifest) Lineage(ids []string, up, down int) *LineageGraph {
+	g := &LineageGraph{byID: map[string]*LineageNode{}}
+	for _, id := range ids {
+		if n, ok := m.Node(id); ok {
+			g.add(n, 0)
+			g.byID[id].Selected = true
+		}
+	}
+
+	walk := func(limit, step int, next func(string) []*Node) {
+		frontier := append([]string(nil), ids...)
+		for depth := 1; len(frontier) > 0 && (limit < 0 || depth <= limit); depth++ {
+			var found []string
+			for _, id := range frontier {
+				for _, n := range next(id) {
+					if _, seen := g.byID[n.UniqueID]; !seen {
+						g.add(n, depth*step)
+						found = append(found, n.UniqueID)
+					}
+				}
+			}
+			frontier = found
+		}
+	}
+	walk(up, -1, m.Parents)
+	walk(down, 1, m.Children)
+
+	for _, ln := range g.Nodes {
+		for _, p := range m.Parents(ln.Node.UniqueID) {
+			if _, ok := g.byID[p.UniqueID]; ok {
+				g.Edges = append(g.Edges, LineageEdge{From: p.UniqueID, To: ln.Node.UniqueID})
+			}
+		}
+	}
+
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Depth != g.Nodes[j].Depth {
+			return g.Nodes[i].Depth < g.Nodes[j].Depth
+		}
+		return g.Nodes[i].Node.UniqueID < g.Nodes[j].Node.UniqueID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+func (g *LineageGraph) add(n *Node, depth int) {
+	ln := &LineageNode{Node: n, Depth: depth, Bytes: -1}
+	g.Nodes = append(g.Nodes, ln)
+	g.byID[n.UniqueID] = ln
+}
+
+// Get returns the graph node with the given unique_id
+func (g *LineageGraph) Get(id string) (*LineageNode, bool) {
+	ln, ok := g.byID[id]
+	return ln, ok
+}
+
+// Kind is the materialization of a model, or the resource type of anything else
+func (ln *LineageNode) Kind() string {
+	if ln.Node.ResourceType == "model" && ln.Node.Config.Materialized != "" {
+		return ln.Node.Config.Materialized
+	}
+	return ln.Node.ResourceType
+}
+
+// lineageColors maps a node kind to a fill colour for DOT and Mermaid, and a terminal colour
+var lineageColors = map[string][2]string{
+	"table":             {"#a6cee3", Blue},
+	"view":              {"#b2df8a", Green},
+	"incremental":       {"#fdbf6f", Yellow},
+	"ephemeral":         {"#e0e0e0", Dim},
+	"materialized_view": {"#cab2d6", Magenta},
+	"source":            {"#fb9a99", Red},
+	"seed":              {"#ffff99", BrightYellow},
+	"snapshot":          {"#cab2d6", Cyan},
+}
+
+func lineageColor(kind string, terminal bool) string {
+	c, ok := lineageColors[kind]
+	if !ok {
+		c = [2]string{"#ffffff", White}
+	}
+	if terminal {
+		return c[1]
+	}
+	return c[0]
+}
+
+// DependencyTree builds the tree of a node's parents (up) or children, at most
+// depth levels deep (negative for no limit). A node reached a second time, as in
+// a diamond dependency, is shown once more as a back-reference but not expanded.
+func (m *Manifest) DependencyTree(id string, up bool, depth int) *TreeNode {
+	next := m.Children
+	if up {
+		next = m.Parents
+	}
+	seen := map[string]bool{}
+
+	var build func(n *Node, level int) *TreeNode
+	build = func(n *Node, level int) *TreeNode {
+		t := &TreeNode{Label: treeLabel(n)}
+		if seen[n.UniqueID] {
+			t.Label = Dim + n.DisplayName() + " ↑ (see above)" + Reset
+			return t
+		}
+
+		children := next(n.UniqueID)
+		if depth >= 0 && level >= depth {
+			if len(children) > 0 {
+				t.Label += Dim + fmt.Sprintf(" … %d more", len(children)) + Reset
+			}
+			return t
+		}
+		// only an expanded node counts as seen, so one first reached at the depth
+		// cutoff is still expanded where it appears higher up
+		seen[n.UniqueID] = true
+		for _, c := range children {
+			t.Children = append(t.Children, build(c, level+1))
+		}
+		return t
+	}
+
+	n, ok := m.Node(id)
+	if !ok {
+		return &TreeNode{Label: id}
+	}
+	return build(n, 0)
+}
+
+func treeLabel(n *Node) string {
+	ln := &LineageNode{Node: n}
+	label := lineageColor(ln.Kind(), true) + n.DisplayName() + Reset + Dim + " " + ln.Kind() + Reset
+	if tags := n.AllTags(); len(tags) > 0 {
+		label += Cyan + " [" + strings.Join(tags, ", ") + "]" + Reset
+	}
+	return label
+}
+
+func (ln *LineageNode) label() string {
+	if ln.Bytes >= 0 {
+		return fmt.Sprintf("%s\n%s", ln.Node.DisplayName(), StripANSI(FormatBytes(ln.Bytes)))
+	}
+	return ln.Node.DisplayName()
+}
+
+// DOT renders the graph for Graphviz
+func (g *LineageGraph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph lineage {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box, style=\"rounded,filled\", fontname=\"Helvetica\"];\n")
+	for _, ln := range g.Nodes {
+		attrs := fmt.Sprintf("label=%q, fillcolor=%q, tooltip=%q", ln.label(), lineageColor(ln.Kind(), false), ln.Kind())
+		if ln.Selected {
+			attrs += ", penwidth=3"
+		}
+		fmt.Fprintf(&sb, "  %q [%s];\n", ln.Node.UniqueID, attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %q -> %q;\n", e.From, e.To)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which GitHub renders in PRs
+func (g *LineageGraph) Mermaid() string {
+	ids := map[string]string{}
+	for i, ln := range g.Nodes {
+		ids[ln.Node.UniqueID] = fmt.Sprintf("n%d", i)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	kinds := map[string]bool{}
+	for _, ln := range g.Nodes {
+		label := strings.ReplaceAll(ln.label(), "\n", "<br/>")
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		fmt.Fprintf(&sb, "  %s[\"%s\"]:::%s\n", ids[ln.Node.UniqueID], label, mermaidClass(ln.Kind()))
+		kinds[ln.Kind()] = true
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %s --> %s\n", ids[e.From], ids[e.To])
+	}
+	kindNames := make([]string, 0, len(kinds))
+	for k := range kinds {
+		kindNames = append(kindNames, k)
+	}
+	sort.Strings(kindNames)
+	for _, k := range kindNames {
+		fmt.Fprintf(&sb, "  classDef %s fill:%s,stroke:#333\n", mermaidClass(k), lineageColor(k, false))
+	}
+	for _, ln := range g.Nodes {
+		if ln.Selected {
+			fmt.Fprintf(&sb, "  style %s stroke-width:3px\n", ids[ln.Node.UniqueID])
+		}
+	}
+	return sb.String()
+}
+
+// mermaidClass avoids class names Mermaid reserves, like "end"
+func mermaidClass(kind string) string {
+	return "dbt_" + kind
+}
+
+// JSON renders the graph as {"nodes": [...], "edges": [...]}
+func (g *LineageGraph) JSON() (string, error) {
+	type jsonNode struct {
+		ID           string   `json:"unique_id"`
+		Name         string   `json:"name"`
+		ResourceType string   `json:"resource_type"`
+		Materialized string   `json:"materialized,omitempty"`
+		Depth        int      `json:"depth"`
+		Selected     bool     `json:"selected"`
+		Tags         []string `json:"tags,omitempty"`
+		Bytes        *int64   `json:"bytes_processed,omitempty"`
+	}
+	type jsonEdge struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	out := struct {
+		Nodes []jsonNode `json:"nodes"`
+		Edges []jsonEdge `json:"edges"`
+	}{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+
+	for _, ln := range g.Nodes {
+		jn := jsonNode{
+			ID:           ln.Node.UniqueID,
+			Name:         ln.Node.DisplayName(),
+			ResourceType: ln.Node.ResourceType,
+			Materialized: ln.Node.Config.Materialized,
+			Depth:        ln.Depth,
+			Selected:     ln.Selected,
+			Tags:         ln.Node.AllTags(),
+		}
+		if ln.Bytes >= 0 {
+			b := ln.Bytes
+			jn.Bytes = &b
+		}
+		out.Nodes = append(out.Nodes, jn)
+	}
+	for _, e := range g.Edges {
+		out.Edges = append(out.Edges, jsonEdge{From: e.From, To: e.To})
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// ASCII renders the graph for the terminal, one line per node grouped by depth
+func (g *LineageGraph) ASCII() string {
+	var sb strings.Builder
+	depth, first := 0, true
+	for _, ln := range g.Nodes {
+		if first || ln.Depth != depth {
+			if !first {
+				sb.WriteString("\n")
+			}
+			depth, first = ln.Depth, false
+			sb.WriteString(Bold + BrightBlue + depthTitle(depth) + Reset + "\n")
+		}
+
+		marker := "  ○ "
+		if ln.Selected {
+			marker = "  " + Bold + "● "
+		}
+		sb.WriteString(marker + lineageColor(ln.Kind(), true) + ln.Node.DisplayName() + Reset)
+		sb.WriteString(Dim + " " + ln.Kind() + Reset)
+		if ln.Bytes >= 0 {
+			sb.WriteString("  " + FormatBytes(ln.Bytes))
+		}
+
+		var parents []string
+		for _, e := range g.Edges {
+			if e.To == ln.Node.UniqueID {
+				parents = append(parents, g.byID[e.From].Node.DisplayName())
+			}
+		}
+		if len(parents) > 0 {
+			sb.WriteString(Dim + "  ← " + strings.Join(parents, ", ") + Reset)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func depthTitle(depth int) string {
+	switch {
+	case depth < 0:
+		return fmt.Sprintf("Upstream %d", -depth)
+	case depth > 0:
+		return fmt.Sprintf("Downstream %d", depth)
+	default:
+		return "Selected"
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lineageManifest is shop.orders → stg (view) → fct (incremental) → rpt (table),
+// with stg → other (table) on the side
+func lineageManifest() *Manifest {
+	model := func(name, materialized string, parents ...string) *Node {
+		n := &Node{UniqueID: "model.p." + name, Name: name, ResourceType: "model"}
+		n.Config.Materialized = materialized
+		n.DependsOn.Nodes = parents
+		return n
+	}
+	return &Manifest{
+		Nodes: map[string]*Node{
+			"model.p.stg":   model("stg", "view", "source.p.shop.orders"),
+			"model.p.fct":   model("fct", "incremental", "model.p.stg"),
+			"model.p.rpt":   model("rpt", "table", "model.p.fct"),
+			"model.p.other": model("other", "table", "model.p.stg"),
+		},
+		Sources: map[string]*Node{
+			"source.p.shop.orders": {UniqueID: "source.p.shop.orders", Name: "orders", SourceName: "shop", ResourceType: "source"},
+		},
+	}
+}
+
+func TestLineage(t *testing.T) {
+	tests := []struct {
+		name     string
+		up, down int
+		want     string // name:depth
+	}{
+		{"one level", 1, 1, "stg:-1 fct:0 rpt:1"},
+		{"all upstream", -1, 0, "shop.orders:-2 stg:-1 fct:0"},
+		{"selection only", 0, 0, "fct:0"},
+		{"everything", -1, -1, "shop.orders:-2 stg:-1 fct:0 rpt:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := lineageManifest().Lineage([]string{"model.p.fct"}, tt.up, tt.down)
+			var got []string
+			for _, ln := range g.Nodes {
+				got = append(got, fmt.Sprintf("%s:%d", ln.Node.DisplayName(), ln.Depth))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Fatalf("Lineage() nodes = %s, want %s", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func testGraph() *LineageGraph {
+	g := lineageManifest().Lineage([]string{"model.p.fct"}, 1, 1)
+	ln, _ := g.Get("model.p.fct")
+	ln.Bytes = 2 << 20
+	return g
+}
+
+func TestLineageDOT(t *testing.T) {
+	want := `digraph lineage {
+  rankdir=LR;
+  node [shape=box, style="rounded,filled", fontname="Helvetica"];
+  "model.p.stg" [label="stg", fillcolor="#b2df8a", tooltip="view"];
+  "model.p.fct" [label="fct\n2.00 MiB", fillcolor="#fdbf6f", tooltip="incremental", penwidth=3];
+  "model.p.rpt" [label="rpt", fillcolor="#a6cee3", tooltip="table"];
+  "model.p.fct" -> "model.p.rpt";
+  "model.p.stg" -> "model.p.fct";
+}
+`
+	if got := testGraph().DOT(); got != want {
+		t.Fatalf("DOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLineageMermaid(t *testing.T) {
+	want := `flowchart LR
+  n0["stg"]:::dbt_view
+  n1["fct<br/>2.00 MiB"]:::dbt_incremental
+  n2["rpt"]:::dbt_table
+  n1 --> n2
+  n0 --> n1
+  classDef dbt_incremental fill:#fdbf6f,stroke:#333
+  classDef dbt_table fill:#a6cee3,stroke:#333
+  classDef dbt_view fill:#b2df8a,stroke:#333
+  style n1 stroke-width:3px
+`
+	if got := testGraph().Mermaid(); got != want {
+		t.Fatalf("Mermaid() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLineageJSON(t *testing.T) {
+	out, err := testGraph().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Nodes []struct {
+			ID       string `json:"unique_id"`
+			Depth    int    `json:"depth"`
+			Selected bool   `json:"selected"`
+			Bytes    *int64 `json:"bytes_processed"`
+		} `json:"nodes"`
+		Edges []struct{ From, To string } `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("JSON() isn't valid JSON: %v\n%s", err, out)
+	}
+	if len(got.Nodes) != 3 || len(got.Edges) != 2 {
+		t.Fatalf("JSON() has %d nodes and %d edges, want 3 and 2", len(got.Nodes), len(got.Edges))
+	}
+	fct := got.Nodes[1]
+	if fct.ID != "model.p.fct" || !fct.Selected || fct.Depth != 0 || fct.Bytes == nil || *fct.Bytes != 2<<20 {
+		t.Errorf("JSON() selected node = %+v", fct)
+	}
+	if got.Nodes[0].Bytes != nil {
+		t.Errorf("JSON() gives bytes_processed for a node that wasn't dry run")
+	}
+	if got.Edges[1].From != "model.p.stg" || got.Edges[1].To != "model.p.fct" {
+		t.Errorf("JSON() edges = %+v", got.Edges)
+	}
+}
+
+func TestLineageASCII(t *testing.T) {
+	want := `Upstream 1
+  ○ stg view
+
+Selected
+  ● fct incremental  2.00 MiB  ← stg
+
+Downstream 1
+  ○ rpt table  ← fct
+`
+	if got := StripANSI(testGraph().ASCII()); got != want {
+		t.Fatalf("ASCII() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDependencyTree(t *testing.T) {
+	model := func(name string, parents ...string) *Node {
+		n := &Node{UniqueID: "model.p." + name, Name: name, ResourceType: "model"}
+		n.Config.Materialized = "table"
+		for _, p := range parents {
+			n.DependsOn.Nodes = append(n.DependsOn.Nodes, "model.p."+p)
+		}
+		return n
+	}
+	// a → b → c → d, and a → c directly
+	m := &Manifest{Nodes: map[string]*Node{
+		"model.p.a": model("a"),
+		"model.p.b": model("b", "a"),
+		"model.p.c": model("c", "a", "b"),
+		"model.p.d": model("d", "c"),
+	}}
+
+	var render func(n *TreeNode, indent string) string
+	render = func(n *TreeNode, indent string) string {
+		s := indent + StripANSI(n.Label) + "\n"
+		for _, c := range n.Children {
+			s += render(c, indent+"  ")
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		depth int
+		want  string
+	}{
+		{"unlimited", -1, `a table
+  b table
+    c table
+      d table
+  c ↑ (see above)
+`},
+		{"first reached at the cutoff", 2, `a table
+  b table
+    c table … 1 more
+  c table
+    d table
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(m.DependencyTree("model.p.a", false, tt.depth), ""); got != tt.want {
+				t.Fatalf("DependencyTree() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
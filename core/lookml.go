@@ -0,0 +1,479 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// LookMLBlock is a `key: name { ... }` block of a LookML file, or the file itself
+type LookMLBlock struct {
+	Key    string // e.g. "view", "dimension", "derived_table"
+	Name   string // "" for anonymous blocks like derived_table
+	Line   int
+	Values map[string]LookMLValue
+	Blocks []*LookMLBlock
+}
+
+// LookMLValue is the raw value of a `key: value` pair, with ;; and quotes
+// removed, and the line it starts on
+type LookMLValue struct {
+	Value string
+	Line  int
+}
+
+// LookMLView is a view with the file it was read from
+type LookMLView struct {
+	*LookMLBlock
+	Path string
+}
+
+// ParseLookML parses the subset of LookML needed to find views and the
+// columns their fields select: blocks, `key: value` pairs and sql values
+// terminated by ;;
+func ParseLookML(src string) (*LookMLBlock, error) {
+	p := &lookmlParser{src: []rune(src), line: 1}
+	root := &LookMLBlock{Line: 1, Values: map[string]LookMLValue{}}
+	if err := p.parseBlock(root, false); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+type lookmlParser struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+func (p *lookmlParser) peek() rune {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *lookmlParser) next() rune {
+	r := p.peek()
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+func (p *lookmlParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+// skipSpace skips whitespace and # comments
+func (p *lookmlParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch r := p.peek(); {
+		case r == '#':
+			for p.pos < len(p.src) && p.peek() != '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *lookmlParser) word() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := p.peek()
+		if unicode.IsSpace(r) || strings.ContainsRune("{}:[],", r) {
+			break
+		}
+		p.next()
+	}
+	return string(p.src[start:p.pos])
+}
+
+// until consumes up to and including end, returning what came before it
+func (p *lookmlParser) until(end string) (string, error) {
+	start, line := p.pos, p.line
+	for p.pos < len(p.src) {
+		if strings.HasPrefix(string(p.src[p.pos:min(p.pos+len(end), len(p.src))]), end) {
+			s := string(p.src[start:p.pos])
+			for range end {
+				p.next()
+			}
+			return s, nil
+		}
+		p.next()
+	}
+	p.line = line
+	return "", p.errorf("missing closing %q", end)
+}
+
+// quoted consumes a string whose opening quote has been read, up to and
+// including the closing quote, and returns it with \" and \\ unescaped
+func (p *lookmlParser) quoted() (string, error) {
+	line := p.line
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		switch r := p.next(); r {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			e := p.next()
+			if e != '"' && e != '\\' {
+				sb.WriteRune(r) // other escapes are kept as written
+			}
+			if e != 0 {
+				sb.WriteRune(e)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	p.line = line
+	return "", p.errorf("missing closing %q", `"`)
+}
+
+// list consumes a [ ... ] list whose opening bracket has been read, up to and
+// including the closing bracket, and returns the raw items; a ] inside a
+// quoted item doesn't end the list
+func (p *lookmlParser) list() (string, error) {
+	start, line := p.pos, p.line
+	for p.pos < len(p.src) {
+		switch p.next() {
+		case ']':
+			return string(p.src[start : p.pos-1]), nil
+		case '"':
+			if _, err := p.quoted(); err != nil {
+				return "", err
+			}
+		}
+	}
+	p.line = line
+	return "", p.errorf("missing closing %q", "]")
+}
+
+// isSQLKey reports whether a key's value is SQL or HTML terminated by ;;
+func isSQLKey(key string) bool {
+	return key == "sql" || strings.HasPrefix(key, "sql_") || strings.HasSuffix(key, "_sql") ||
+		key == "html" || key == "expression"
+}
+
+func (p *lookmlParser) parseBlock(b *LookMLBlock, nested bool) error {
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case 0:
+			if nested {
+				return p.errorf("missing } for %s: %s", b.Key, b.Name)
+			}
+			return nil
+		case '}':
+			if !nested {
+				return p.errorf("unexpected }")
+			}
+			p.next()
+			return nil
+		}
+
+		line := p.line
+		key := p.word()
+		if key == "" {
+			return p.errorf("unexpected %q", p.peek())
+		}
+		p.skipSpace()
+		if p.next() != ':' {
+			return p.errorf("expected : after %s", key)
+		}
+		p.skipSpace()
+		valueLine := p.line
+
+		var value string
+		switch r := p.peek(); {
+		case isSQLKey(key):
+			v, err := p.until(";;")
+			if err != nil {
+				return err
+			}
+			value = strings.TrimSpace(v)
+		case r == '{':
+			p.next()
+			child := &LookMLBlock{Key: key, Line: line, Values: map[string]LookMLValue{}}
+			if err := p.parseBlock(child, true); err != nil {
+				return err
+			}
+			b.Blocks = append(b.Blocks, child)
+			continue
+		case r == '"':
+			p.next()
+			v, err := p.quoted()
+			if err != nil {
+				return err
+			}
+			value = v
+		case r == '[':
+			p.next()
+			v, err := p.list()
+			if err != nil {
+				return err
+			}
+			value = "[" + v + "]"
+		default:
+			value = p.word()
+			p.skipSpace()
+			if p.peek() == '{' {
+				p.next()
+				child := &LookMLBlock{Key: key, Name: value, Line: line, Values: map[string]LookMLValue{}}
+				if err := p.parseBlock(child, true); err != nil {
+					return err
+				}
+				b.Blocks = append(b.Blocks, child)
+				continue
+			}
+		}
+
+		// some non-SQL values are written with a trailing ;; too
+		p.skipSpace()
+		if strings.HasPrefix(string(p.src[p.pos:min(p.pos+2, len(p.src))]), ";;") {
+			p.next()
+			p.next()
+		}
+		b.Values[key] = LookMLValue{Value: value, Line: valueLine}
+	}
+}
+
+// Views returns the view blocks in a parsed file
+func (b *LookMLBlock) Views() []*LookMLBlock {
+	var views []*LookMLBlock
+	for _, c := range b.Blocks {
+		if c.Key == "view" {
+			views = append(views, c)
+		}
+	}
+	return views
+}
+
+// LoadLookMLViews parses every .lkml file under dir and returns their views.
+// Files that don't parse are reported through warn and skipped.
+func LoadLookMLViews(dir string, warn func(path string, err error)) ([]LookMLView, error) {
+	var views []LookMLView
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); path != dir && (strings.HasPrefix(name, ".") || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".lkml") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		root, err := ParseLookML(string(data))
+		if err != nil {
+			warn(path, err)
+			return nil
+		}
+		for _, v := range root.Views() {
+			views = append(views, LookMLView{LookMLBlock: v, Path: path})
+		}
+		return nil
+	})
+	return views, err
+}
+
+// LookMLField is a dimension, dimension_group or measure of a view
+type LookMLField struct {
+	Type    string // "dimension", "dimension_group" or "measure"
+	Name    string
+	Line    int
+	Columns []string // columns of ${TABLE} the field's sql selects
+}
+
+var lookmlFieldTypes = map[string]bool{"dimension": true, "dimension_group": true, "measure": true, "filter": true, "parameter": true}
+
+// tableRefRegex matches ${TABLE}.column, ${TABLE}.`column` and ${TABLE}.record.field
+var tableRefRegex = regexp.MustCompile("(?i)\\$\\{TABLE\\}\\.`?([A-Za-z_][A-Za-z0-9_]*(?:\\.[A-Za-z_][A-Za-z0-9_]*)*)`?")
+
+// Fields returns the fields of a view and the ${TABLE} columns each selects.
+// A dimension without sql selects the column of the same name.
+func (b *LookMLBlock) Fields() []LookMLField {
+	var fields []LookMLField
+	for _, c := range b.Blocks {
+		if !lookmlFieldTypes[c.Key] {
+			continue
+		}
+		f := LookMLField{Type: c.Key, Name: c.Name, Line: c.Line}
+		sql, hasSQL := c.Values["sql"]
+		switch {
+		case hasSQL:
+			seen := map[string]bool{}
+			for _, m := range tableRefRegex.FindAllStringSubmatch(sql.Value, -1) {
+				col := strings.ToLower(m[1])
+				if !seen[col] {
+					seen[col] = true
+					f.Columns = append(f.Columns, col)
+				}
+			}
+		case c.Key == "dimension" || c.Key == "dimension_group":
+			f.Columns = []string{strings.ToLower(c.Name)}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// SQLTableName returns the view's sql_table_name, without backticks
+func (b *LookMLBlock) SQLTableName() string {
+	return strings.TrimSpace(strings.ReplaceAll(b.Values["sql_table_name"].Value, "`", ""))
+}
+
+// DerivedTableSQL returns the sql of the view's derived_table, if it has one
+func (b *LookMLBlock) DerivedTableSQL() (LookMLValue, bool) {
+	for _, c := range b.Blocks {
+		if c.Key == "derived_table" {
+			v, ok := c.Values["sql"]
+			return v, ok
+		}
+	}
+	return LookMLValue{}, false
+}
+
+// LookMLReference is a place a view selects from a table
+type LookMLReference struct {
+	View LookMLView
+	Key  string // "sql_table_name" or "derived_table"
+	Line int
+	Text string // the matched reference
+}
+
+// lookmlTemplate matches a Liquid expression or LookML constant standing in for
+// a project or dataset, e.g. {{ _user_attributes['project'] }} or @{schema}
+const lookmlTemplate = `(?:\{\{[^}]*\}\}|@\{[^}]*\})`
+
+// TableRefRegex matches references to project.dataset.table or dataset.table,
+// with or without backticks, where the project or dataset may be templated
+func TableRefRegex(project, dataset, table string) *regexp.Regexp {
+	part := func(s string) string { return "`?" + regexp.QuoteMeta(s) + "`?" }
+	proj := "(?:" + part(project) + "|`?" + lookmlTemplate + "`?)"
+	ds := "(?:" + part(dataset) + "|`?" + lookmlTemplate + "`?)"
+	return regexp.MustCompile("(?i)(?:^|[^\\w.])((?:" + proj + "\\.)?" + ds + "\\." + part(table) + ")(?:[^\\w.]|$)")
+}
+
+// FindTableReferences returns the views whose sql_table_name or derived table
+// SQL selects from project.dataset.table
+func FindTableReferences(views []LookMLView, project, dataset, table string) []LookMLReference {
+	re := TableRefRegex(project, dataset, table)
+	var refs []LookMLReference
+	for _, v := range views {
+		check := func(key string, value LookMLValue) {
+			for _, m := range re.FindAllStringSubmatchIndex(value.Value, -1) {
+				refs = append(refs, LookMLReference{
+					View: v,
+					Key:  key,
+					Line: value.Line + strings.Count(value.Value[:m[2]], "\n"),
+					Text: value.Value[m[2]:m[3]],
+				})
+			}
+		}
+		if v, ok := v.Values["sql_table_name"]; ok {
+			check("sql_table_name", v)
+		}
+		if sql, ok := v.DerivedTableSQL(); ok {
+			check("derived_table", sql)
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].View.Path != refs[j].View.Path {
+			return refs[i].View.Path < refs[j].View.Path
+		}
+		return refs[i].Line < refs[j].Line
+	})
+	return refs
+}
+
+// FindViewForTable returns the views whose sql_table_name is project.dataset.table,
+// or failing that the views named after the table
+func FindViewForTable(views []LookMLView, project, dataset, table string) []LookMLView {
+	var matches []LookMLView
+	for _, ref := range FindTableReferences(views, project, dataset, table) {
+		if ref.Key == "sql_table_name" {
+			matches = append(matches, ref.View)
+		}
+	}
+	if len(matches) == 0 {
+		for _, v := range views {
+			if strings.EqualFold(v.Name, table) {
+				matches = append(matches, v)
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+	return matches
+}
+
+// LookMLDiff compares the columns a view's fields select with a model's columns
+type LookMLDiff struct {
+	Missing   []LookMLMissing // selected by LookML, not in the model
+	Unexposed []string        // in the model, not selected by any field
+}
+
+// LookMLMissing is a column a field selects that the model doesn't have
+type LookMLMissing struct {
+	Field  LookMLField
+	Column string
+}
+
+// DiffLookML compares a view's fields with a model's (dotted, lower-case) column names
+func DiffLookML(fields []LookMLField, columns []string) LookMLDiff {
+	have := map[string]bool{}
+	for _, c := range columns {
+		have[strings.ToLower(c)] = true
+	}
+
+	var diff LookMLDiff
+	used := map[string]bool{}
+	for _, f := range fields {
+		for _, col := range f.Columns {
+			used[col] = true
+			// a field of a RECORD counts as using the record
+			if root, _, ok := strings.Cut(col, "."); ok {
+				used[root] = true
+			}
+			if !have[col] {
+				diff.Missing = append(diff.Missing, LookMLMissing{Field: f, Column: col})
+			}
+		}
+	}
+	for _, c := range columns {
+		c = strings.ToLower(c)
+		if used[c] {
+			continue
+		}
+		// a record is exposed if any of its fields are
+		exposed := false
+		for u := range used {
+			if strings.HasPrefix(u, c+".") || strings.HasPrefix(c, u+".") {
+				exposed = true
+				break
+			}
+		}
+		if !exposed {
+			diff.Unexposed = append(diff.Unexposed, c)
+		}
+	}
+	sort.Strings(diff.Unexposed)
+	return diff
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ordersLookML = `# orders view
+view: orders {
+  sql_table_name: ` + "`my-proj.analytics.orders`" + ` ;;
+  label: "He said \"hi\" \\ bye"
+
+  dimension: id {
+    primary_key: yes
+    type: number
+    sql: ${TABLE}.id ;;
+  }
+
+  dimension: status {
+    tags: ["a]b", "c"]
+    html: <b>{{ value }}</b> ;;
+  }
+
+  dimension_group: created {
+    type: time
+    timeframes: [date, week]
+    sql: ${TABLE}.` + "`created_at`" + ` ;;
+  }
+
+  measure: total {
+    type: sum
+    sql: ${TABLE}.amount + ${TABLE}.tax + ${TABLE}.amount ;;
+  }
+
+  measure: count {
+    type: count
+  }
+}
+
+view: +orders {
+  derived_table: {
+    sql: select *
+      from analytics.orders ;;
+  }
+}
+`
+
+func TestParseLookML(t *testing.T) {
+	root, err := ParseLookML(ordersLookML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views := root.Views()
+	if len(views) != 2 || views[0].Name != "orders" || views[1].Name != "+orders" {
+		t.Fatalf("Views() = %d views, want orders and +orders", len(views))
+	}
+	v := views[0]
+	if v.Line != 2 {
+		t.Errorf("view line = %d, want 2", v.Line)
+	}
+	if got := v.Values["label"].Value; got != `He said "hi" \ bye` {
+		t.Errorf("label = %q, want the escapes unescaped", got)
+	}
+	if got := v.SQLTableName(); got != "my-proj.analytics.orders" {
+		t.Errorf("SQLTableName() = %q", got)
+	}
+	status := v.Blocks[1]
+	if got := status.Values["tags"].Value; got != `["a]b", "c"]` {
+		t.Errorf("tags = %q, want the whole list", got)
+	}
+	if got := status.Values["html"].Value; got != "<b>{{ value }}</b>" {
+		t.Errorf("html = %q", got)
+	}
+
+	sql, ok := views[1].DerivedTableSQL()
+	if !ok || sql.Line != 35 || !strings.HasSuffix(sql.Value, "from analytics.orders") {
+		t.Errorf("DerivedTableSQL() = %+v, %t, want the sql from line 35", sql, ok)
+	}
+}
+
+func TestLookMLFields(t *testing.T) {
+	root, err := ParseLookML(ordersLookML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LookMLField{
+		{Type: "dimension", Name: "id", Line: 6, Columns: []string{"id"}},
+		{Type: "dimension", Name: "status", Line: 12, Columns: []string{"status"}},
+		{Type: "dimension_group", Name: "created", Line: 17, Columns: []string{"created_at"}},
+		{Type: "measure", Name: "total", Line: 23, Columns: []string{"amount", "tax"}},
+		{Type: "measure", Name: "count", Line: 28},
+	}
+	if got := root.Views()[0].Fields(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fields() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseLookMLErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"unclosed block", "view: a {\n  dimension: b {\n", "line 3: missing } for dimension: b"},
+		{"unclosed string", "view: a {\n  label: \"oops\n}\n", `line 2: missing closing "\""`},
+		{"escaped quote at the end", `view: a { label: "oops\" }`, `line 1: missing closing "\""`},
+		{"unclosed list", "view: a {\n  tags: [\"x\"\n}\n", `line 2: missing closing "]"`},
+		{"unterminated sql", "view: a {\n  sql_table_name: t\n}\n", `line 2: missing closing ";;"`},
+		{"stray brace", "}", "line 1: unexpected }"},
+		{"missing colon", "view a {}", "line 1: expected : after view"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLookML(tt.src)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("ParseLookML() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLookML(t *testing.T) {
+	fields := []LookMLField{
+		{Name: "id", Columns: []string{"id"}},
+		{Name: "city", Columns: []string{"address.city"}},
+		{Name: "gone", Columns: []string{"old_col"}},
+	}
+	diff := DiffLookML(fields, []string{"ID", "address", "address.city", "address.zip", "amount"})
+	if len(diff.Missing) != 1 || diff.Missing[0].Column != "old_col" || diff.Missing[0].Field.Name != "gone" {
+		t.Errorf("Missing = %+v, want old_col from gone", diff.Missing)
+	}
+	if want := []string{"amount"}; !reflect.DeepEqual(diff.Unexposed, want) {
+		t.Errorf("Unexposed = %v, want %v", diff.Unexposed, want)
+	}
+}
+
+func TestTableRefRegex(t *testing.T) {
+	re := TableRefRegex("my-proj", "analytics", "orders")
+	tests := []struct {
+		text  string
+		match string
+	}{
+		{"my-proj.analytics.orders", "my-proj.analytics.orders"},
+		{"`my-proj.analytics.orders`", "`my-proj.analytics.orders`"},
+		{"`my-proj`.`analytics`.`orders`", "`my-proj`.`analytics`.`orders`"},
+		{"analytics.orders", "analytics.orders"},
+		{"ANALYTICS.Orders", "ANALYTICS.Orders"},
+		{"select * from analytics.orders o", "analytics.orders"},
+		{"{{ _user_attributes['project'] }}.analytics.orders", "{{ _user_attributes['project'] }}.analytics.orders"},
+		{"@{project}.@{schema}.orders", "@{project}.@{schema}.orders"},
+		{"`@{schema}.orders`", "`@{schema}.orders`"},
+		{"analytics.orders_v2", ""},
+		{"other.orders", ""},
+		{"analytics.orders.id", ""},
+		{"old_analytics.orders", ""},
+		{"elsewhere.analytics.orders", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got string
+			if m := re.FindStringSubmatch(tt.text); m != nil {
+				got = m[1]
+			}
+			if got != tt.match {
+				t.Fatalf("TableRefRegex matched %q in %q, want %q", got, tt.text, tt.match)
+			}
+		})
+	}
+}
+
+func TestFindTableReferences(t *testing.T) {
+	root, err := ParseLookML(ordersLookML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var views []LookMLView
+	for _, v := range root.Views() {
+		views = append(views, LookMLView{LookMLBlock: v, Path: "orders.view.lkml"})
+	}
+
+	refs := FindTableReferences(views, "my-proj", "analytics", "orders")
+	var got []string
+	for _, r := range refs {
+		got = append(got, fmt.Sprintf("%s %s:%d", r.View.Name, r.Key, r.Line))
+	}
+	want := []string{"orders sql_table_name:3", "+orders derived_table:36"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FindTableReferences() = %v, want %v", got, want)
+	}
+
+	matches := FindViewForTable(views, "my-proj", "analytics", "orders")
+	if len(matches) != 1 || matches[0].Name != "orders" {
+		t.Fatalf("FindViewForTable() = %d views, want orders", len(matches))
+	}
+	if got := FindTableReferences(views, "my-proj", "analytics", "customers"); len(got) != 0 {
+		t.Fatalf("FindTableReferences() for another table = %v, want none", got)
+	}
+}
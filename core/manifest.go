@@ -0,0 +1,314 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest is the subset of dbt's target/manifest.json that dibbity uses
+type Manifest struct {
+	Metadata struct {
+		ProjectName string `json:"project_name"`
+	} `json:"metadata"`
+	Nodes     map[string]*Node    `json:"nodes"`
+	Sources   map[string]*Node    `json:"sources"`
+	ParentMap map[string][]string `json:"parent_map"`
+	ChildMap  map[string][]string `json:"child_map"`
+}
+
+// Node is a model, seed, snapshot, test or source in the manifest
+type Node struct {
+	UniqueID         string             `json:"unique_id"`
+	Name             string             `json:"name"`
+	ResourceType     string             `json:"resource_type"`
+	PackageName      string             `json:"package_name"`
+	Path             string             `json:"path"`
+	OriginalFilePath string             `json:"original_file_path"`
+	PatchPath        string             `json:"patch_path"`
+	CompiledPath     string             `json:"compiled_path"`
+	Database         string             `json:"database"`
+	Schema           string             `json:"schema"`
+	Alias            string             `json:"alias"`
+	Identifier       string             `json:"identifier"` // sources only
+	SourceName       string             `json:"source_name"`
+	RelationName     string             `json:"relation_name"`
+	Description      string             `json:"description"`
+	Tags             []string           `json:"tags"`
+	Meta             map[string]any     `json:"meta"`
+	Columns          map[string]*Column `json:"columns"`
+	Config           NodeConfig         `json:"config"`
+	DependsOn        struct {
+		Nodes []string `json:"nodes"`
+	} `json:"depends_on"`
+	AttachedNode string `json:"attached_node"` // tests only
+	ColumnName   string `json:"column_name"`   // tests only, "" for model-level tests
+	TestMetadata struct {
+		Name string `json:"name"`
+	} `json:"test_metadata"` // generic tests only, e.g. not_null
+}
+
+// NodeConfig holds the node config we care about
+type NodeConfig struct {
+	Materialized string         `json:"materialized"`
+	Tags         any            `json:"tags"` // string or list in dbt configs
+	Meta         map[string]any `json:"meta"`
+}
+
+// Column is a documented column from schema.yml
+type Column struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	DataType    string         `json:"data_type"`
+	Meta        map[string]any `json:"meta"`
+	Tags        []string       `json:"tags"`
+}
+
+// ErrNoManifest is returned when a command needs manifest.json but none could be loaded
+var ErrNoManifest = &NotFoundError{Kind: "manifest", Name: "target/manifest.json (run `dbt compile` or `dbt parse` first)"}
+
+// ManifestPath returns the path of manifest.json in the dbt project
+func ManifestPath(dbtDir string) string {
+	return filepath.Join(dbtDir, "target", "manifest.json")
+}
+
+// LoadManifest reads and parses target/manifest.json from the dbt project
+func LoadManifest(dbtDir string, b bool) (*Manifest, error) {
+	p := ManifestPath(dbtDir)
+	LogVerbose(b, "Loading manifest from %s", p)
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &NotFoundError{Kind: "manifest (run `dbt compile` or `dbt parse` first)", Name: p}
+		}
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	for id, n := range m.Nodes {
+		n.UniqueID = id
+	}
+	for id, n := range m.Sources {
+		n.UniqueID = id
+	}
+
+	LogVerbose(b, "Loaded manifest with %d nodes", len(m.Nodes))
+	return &m, nil
+}
+
+// Models returns every model in the manifest sorted by name
+func (m *Manifest) Models() []*Node {
+	var models []*Node
+	for _, n := range m.Nodes {
+		if n.ResourceType == "model" {
+			models = append(models, n)
+		}
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
+
+// Model finds a model by name, preferring the root project over packages
+func (m *Manifest) Model(name string) (*Node, error) {
+	var found *Node
+	for _, n := range m.Nodes {
+		if n.ResourceType != "model" || n.Name != name {
+			continue
+		}
+		if found == nil || n.PackageName == m.Metadata.ProjectName {
+			found = n
+		}
+	}
+	if found == nil {
+		return nil, &NotFoundError{Kind: "model", Name: name}
+	}
+	return found, nil
+}
+
+// Node returns a model or source by unique id
+func (m *Manifest) Node(id string) (*Node, bool) {
+	if n, ok := m.Nodes[id]; ok {
+		return n, true
+	}
+	n, ok := m.Sources[id]
+	return n, ok
+}
+
+// IsIncremental reports whether the node is materialized as an incremental model
+func (n *Node) IsIncremental() bool {
+	return n.Config.Materialized == "incremental"
+}
+
+// CompiledPathIn returns where dbt writes the compiled SQL for the node when
+// compiling into targetPath, i.e. <target>/compiled/<package>/<original_file_path>
+func (n *Node) CompiledPathIn(targetPath string) string {
+	return filepath.Join(targetPath, "compiled", n.PackageName, n.OriginalFilePath)
+}
+
+// ArtefactPath returns the absolute path of the node's SQL for an artefact:
+// "compiled" uses the manifest's compiled_path (falling back to the dbt layout
+// under target/compiled), "run" uses target/run/<package>/<original_file_path>
+func (n *Node) ArtefactPath(dbtDir string, artefact string) string {
+	if artefact == "compiled" && n.CompiledPath != "" {
+		if filepath.IsAbs(n.CompiledPath) {
+			return n.CompiledPath
+		}
+		return filepath.Join(dbtDir, n.CompiledPath)
+	}
+	return filepath.Join(dbtDir, "target", artefact, n.PackageName, n.OriginalFilePath)
+}
+
+// PatchFile returns the absolute path of the YAML file documenting the node,
+// or "" if it isn't documented. dbt writes patch_path as "<package>://<path>".
+func (n *Node) PatchFile(dbtDir string) string {
+	if n.PatchPath == "" {
+		return ""
+	}
+	p := n.PatchPath
+	if _, rest, ok := strings.Cut(p, "://"); ok {
+		p = rest
+	}
+	return filepath.Join(dbtDir, p)
+}
+
+// DisplayName returns the node's name, as "source_name.name" for sources
+func (n *Node) DisplayName() string {
+	if n.ResourceType == "source" {
+		return n.SourceName + "." + n.Name
+	}
+	return n.Name
+}
+
+// TestName returns the generic test a test node runs (e.g. not_null), or its name
+func (n *Node) TestName() string {
+	if n.TestMetadata.Name != "" {
+		return n.TestMetadata.Name
+	}
+	return n.Name
+}
+
+// AllTags returns the node's tags and config tags, sorted and de-duplicated
+func (n *Node) AllTags() []string {
+	seen := map[string]bool{}
+	for _, t := range n.Tags {
+		seen[t] = true
+	}
+	switch t := n.Config.Tags.(type) {
+	case string:
+		seen[t] = true
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				seen[s] = true
+			}
+		}
+	}
+	tags := make([]string, 0, len(seen))
+	for t := range seen {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// AllMeta returns the node's meta merged over its config meta
+func (n *Node) AllMeta() map[string]any {
+	meta := map[string]any{}
+	for k, v := range n.Config.Meta {
+		meta[k] = v
+	}
+	for k, v := range n.Meta {
+		meta[k] = v
+	}
+	return meta
+}
+
+// Tests returns the tests attached to a node, sorted by column then name
+func (m *Manifest) Tests(id string) []*Node {
+	var tests []*Node
+	for _, n := range m.Nodes {
+		if n.ResourceType != "test" {
+			continue
+		}
+		attached := n.AttachedNode == id
+		if n.AttachedNode == "" {
+			// older manifests only link tests through depends_on
+			for _, dep := range n.DependsOn.Nodes {
+				attached = attached || dep == id
+			}
+		}
+		if attached {
+			tests = append(tests, n)
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].ColumnName != tests[j].ColumnName {
+			return tests[i].ColumnName < tests[j].ColumnName
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	return tests
+}
+
+// Parents returns the models, sources, seeds and snapshots a node selects from
+func (m *Manifest) Parents(id string) []*Node {
+	ids := m.ParentMap[id]
+	if ids == nil {
+		if n, ok := m.Node(id); ok {
+			ids = n.DependsOn.Nodes
+		}
+	}
+	return m.resolve(ids)
+}
+
+// Children returns the nodes that select from a node, tests excluded
+func (m *Manifest) Children(id string) []*Node {
+	ids := m.ChildMap[id]
+	if ids == nil {
+		for _, n := range m.Nodes {
+			for _, dep := range n.DependsOn.Nodes {
+				if dep == id {
+					ids = append(ids, n.UniqueID)
+				}
+			}
+		}
+	}
+	return m.resolve(ids)
+}
+
+func (m *Manifest) resolve(ids []string) []*Node {
+	var nodes []*Node
+	for _, id := range ids {
+		if n, ok := m.Node(id); ok && n.ResourceType != "test" {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].UniqueID < nodes[j].UniqueID })
+	return nodes
+}
+
+// SourcePath returns the absolute path of the node's source file
+func (n *Node) SourcePath(dbtDir string) string {
+	return filepath.Join(dbtDir, n.OriginalFilePath)
+}
+
+// IsStale reports whether the artefact at path is older than the source file it was built from
+func IsStale(path string, source string) (bool, error) {
+	a, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	s, err := os.Stat(source)
+	if err != nil {
+		return false, err
+	}
+	return a.ModTime().Before(s.ModTime()), nil
+}
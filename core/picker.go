@@ -0,0 +1,335 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrPickerCancelled is returned when the user leaves the picker without choosing.
+// It wraps context.Canceled so it exits like an interrupt.
+var ErrPickerCancelled = fmt.Errorf("selection cancelled: %w", context.Canceled)
+
+// PickerItem is one entry in the fuzzy picker
+type PickerItem struct {
+	Name         string
+	Path         string
+	Materialized string
+	Description  string
+}
+
+// PickerItemsFromManifest lists every model in the manifest as picker items
+func PickerItemsFromManifest(m *Manifest) []PickerItem {
+	var items []PickerItem
+	for _, n := range m.Models() {
+		items = append(items, PickerItem{
+			Name:         n.Name,
+			Path:         n.OriginalFilePath,
+			Materialized: n.Config.Materialized,
+			Description:  n.Description,
+		})
+	}
+	return items
+}
+
+// FuzzyScore scores how well pattern matches s as a case-insensitive
+// subsequence. Consecutive matches and matches at the start of a word
+// (after _ / . or -) score higher. ok is false if pattern doesn't match.
+func FuzzyScore(pattern string, s string) (score int, ok bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(strings.ToLower(s))
+
+	pi, prev := 0, -2
+	for i, c := range r {
+		if pi == len(p) {
+			break
+		}
+		if c != p[pi] {
+			continue
+		}
+		score++
+		if i == prev+1 {
+			score += 3 // consecutive
+		}
+		if i == 0 || strings.ContainsRune("_/.- ", r[i-1]) {
+			score += 2 // start of a word
+		}
+		prev = i
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score - len(r)/10, true // prefer shorter names on ties
+}
+
+type picker struct {
+	items    []PickerItem
+	multi    bool
+	query    string
+	matches  []int // indexes into items, best match first
+	cursor   int
+	offset   int // first visible match
+	selected map[int]bool
+}
+
+func (p *picker) filter() {
+	type scored struct{ idx, score int }
+	var s []scored
+	for i, it := range p.items {
+		// match on path too, so "marts/fct" narrows by folder
+		score, ok := FuzzyScore(p.query, it.Name)
+		if pathScore, pathOk := FuzzyScore(p.query, it.Path); pathOk && (!ok || pathScore-5 > score) {
+			score, ok = pathScore-5, true
+		}
+		if ok {
+			s = append(s, scored{i, score})
+		}
+	}
+	sort.SliceStable(s, func(i, j int) bool { return s[i].score > s[j].score })
+
+	p.matches = p.matches[:0]
+	for _, m := range s {
+		p.matches = append(p.matches, m.idx)
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *picker) move(delta int, height int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+height {
+		p.offset = p.cursor - height + 1
+	}
+}
+
+func (p *picker) render(width, height int) string {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J") // home + clear
+
+	// prompt
+	b.WriteString(Bold + BrightCyan + "> " + Reset + p.query + "\r\n")
+	help := "↑/↓ move · enter confirm · esc cancel"
+	if p.multi {
+		help = "↑/↓ move · tab toggle · enter confirm · esc cancel"
+	}
+	fmt.Fprintf(&b, "%s  %d/%d  %s%s\r\n", Dim, len(p.matches), len(p.items), help, Reset)
+
+	for row := range height {
+		i := p.offset + row
+		if i >= len(p.matches) {
+			b.WriteString("\r\n")
+			continue
+		}
+		it := p.items[p.matches[i]]
+
+		pointer, mark := "  ", ""
+		if i == p.cursor {
+			pointer = BrightMagenta + "▶ " + Reset
+		}
+		if p.multi {
+			mark = Dim + "○ " + Reset
+			if p.selected[p.matches[i]] {
+				mark = Green + "● " + Reset
+			}
+		}
+
+		line := fmt.Sprintf("%s%s%s%-40s%s %s%-12s%s %s",
+			pointer, mark, Bold, it.Name, Reset,
+			Yellow, it.Materialized, Reset,
+			Dim+it.Path+Reset)
+		b.WriteString(truncateVisual(line, width) + "\r\n")
+	}
+
+	// preview of the highlighted model
+	b.WriteString(Dim + strings.Repeat(BoxChars[BoxSingle][1], max(width, 1)) + Reset + "\r\n")
+	if len(p.matches) > 0 {
+		it := p.items[p.matches[p.cursor]]
+		desc := strings.Join(strings.Fields(it.Description), " ")
+		if desc == "" {
+			desc = Dim + "(no description)" + Reset
+		}
+		b.WriteString(truncateVisual(Bold+it.Name+Reset+"  "+Dim+it.Path+Reset, width) + "\r\n")
+		b.WriteString(truncateVisual(desc, width))
+	}
+	return b.String()
+}
+
+// truncateVisual cuts s to at most width visible characters, keeping ANSI codes intact
+func truncateVisual(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(StripANSI(s)) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	visible, inEscape := 0, false
+	for _, r := range s {
+		if r == '\x1b' {
+			inEscape = true
+		}
+		if inEscape {
+			b.WriteRune(r)
+			if r == 'm' {
+				inEscape = false
+			}
+			continue
+		}
+		if visible == width-1 {
+			break
+		}
+		b.WriteRune(r)
+		visible++
+	}
+	return b.String() + "…" + Reset
+}
+
+// PickModels shows an interactive fuzzy finder over items on the terminal and
+// returns the chosen names. With multi, tab toggles several models; otherwise
+// enter picks the highlighted one. The UI is drawn on stderr so stdout stays
+// clean for piping.
+func PickModels(items []PickerItem, multi bool) ([]string, error) {
+	if len(items) == 0 {
+		return nil, &NotFoundError{Kind: "model", Name: "any (manifest has no models)"}
+	}
+
+	in := int(os.Stdin.Fd())
+	out := os.Stderr
+	if !term.IsTerminal(in) {
+		return nil, errors.New("the model picker needs an interactive terminal")
+	}
+
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return nil, fmt.Errorf("could not start picker: %w", err)
+	}
+	fmt.Fprint(out, "\033[?1049h\033[?25l") // alternate screen, hide cursor
+	defer func() {
+		fmt.Fprint(out, "\033[?25h\033[?1049l")
+		_ = term.Restore(in, state)
+	}()
+
+	p := &picker{items: items, multi: multi, selected: map[int]bool{}}
+	p.filter()
+
+	buf := make([]byte, 16)
+	for {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || width == 0 || height == 0 {
+			width, height = 80, 24
+		}
+		listHeight := max(height-5, 1) // prompt, help, separator, preview x2
+		fmt.Fprint(out, p.render(width, listHeight))
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range splitKeys(buf[:n]) {
+			names, done := p.handleKey(key, listHeight)
+			if done {
+				if names == nil {
+					return nil, ErrPickerCancelled
+				}
+				return names, nil
+			}
+		}
+	}
+}
+
+// splitKeys splits a read from the terminal into individual key presses,
+// keeping escape sequences such as arrow keys together
+func splitKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		n := 1
+		if b[0] == '\x1b' && len(b) > 1 && (b[1] == '[' || b[1] == 'O') {
+			// CSI / SS3 sequence, ends with a letter or ~
+			n = 2
+			for n < len(b) && !(b[n] >= 'A' && b[n] <= 'Z' || b[n] >= 'a' && b[n] <= 'z' || b[n] == '~') {
+				n++
+			}
+			n = min(n+1, len(b))
+		} else if b[0] >= utf8.RuneSelf {
+			_, n = utf8.DecodeRune(b)
+		}
+		keys = append(keys, string(b[:n]))
+		b = b[n:]
+	}
+	return keys
+}
+
+// handleKey applies a key press. done is true when the picker should close,
+// with nil names meaning it was cancelled.
+func (p *picker) handleKey(key string, listHeight int) (names []string, done bool) {
+	items := p.items
+	switch key {
+	case "\x1b", "\x03", "\x07": // esc, ctrl-c, ctrl-g
+		return nil, true
+	case "\r", "\n":
+		for _, i := range p.matches {
+			if p.selected[i] {
+				names = append(names, items[i].Name)
+			}
+		}
+		// selections hidden by the current query still count
+		for i := range items {
+			if p.selected[i] && !slices.Contains(p.matches, i) {
+				names = append(names, items[i].Name)
+			}
+		}
+		if len(names) == 0 && len(p.matches) > 0 {
+			names = []string{items[p.matches[p.cursor]].Name}
+		}
+		return names, len(names) > 0
+	case "\t":
+		if p.multi && len(p.matches) > 0 {
+			i := p.matches[p.cursor]
+			p.selected[i] = !p.selected[i]
+			p.move(1, listHeight)
+		}
+	case "\x1b[A", "\x1bOA", "\x10": // up, ctrl-p
+		p.move(-1, listHeight)
+	case "\x1b[B", "\x1bOB", "\x0e": // down, ctrl-n
+		p.move(1, listHeight)
+	case "\x1b[5~": // page up
+		p.move(-listHeight, listHeight)
+	case "\x1b[6~": // page down
+		p.move(listHeight, listHeight)
+	case "\x7f", "\x08": // backspace
+		if q := []rune(p.query); len(q) > 0 {
+			p.query = string(q[:len(q)-1])
+			p.filter()
+		}
+	case "\x15": // ctrl-u
+		p.query = ""
+		p.filter()
+	default:
+		if strings.HasPrefix(key, "\x1b") {
+			return nil, false // unhandled escape sequence
+		}
+		for _, r := range key {
+			if unicode.IsPrint(r) {
+				p.query += string(r)
+			}
+		}
+		p.filter()
+	}
+	return nil, false
+}
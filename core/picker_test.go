@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       int
+		ok         bool
+	}{
+		{"", "anything", 0, true},
+		{"fct", "fct_orders", 10, true},
+		{"FCT", "fct_orders", 10, true},
+		{"fo", "fct_orders", 5, true},
+		{"ord", "stg_orders", 10, true},
+		{"xyz", "fct_orders", 0, false},
+		{"of", "fct_orders", 0, false}, // order matters
+		{"fct_orders_", "fct_orders", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.s, func(t *testing.T) {
+			got, ok := FuzzyScore(tt.pattern, tt.s)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("FuzzyScore(%q, %q) = %d, %t, want %d, %t", tt.pattern, tt.s, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	tests := []struct {
+		pattern, better, worse string
+	}{
+		{"ord", "orders", "fct_big_table_of_records"},  // consecutive beats scattered
+		{"cust", "dim_customers", "stg_acme_utils_st"}, // word start beats mid-word
+		{"orders", "orders", "orders_with_a_very_long_suffix_name"},
+	}
+	for _, tt := range tests {
+		b, _ := FuzzyScore(tt.pattern, tt.better)
+		w, _ := FuzzyScore(tt.pattern, tt.worse)
+		if b <= w {
+			t.Errorf("FuzzyScore(%q): %q scored %d, %q scored %d, want the first higher", tt.pattern, tt.better, b, tt.worse, w)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package core
+
+import "os/exec"
+
+// setProcessGroup is a no-op on windows; exec.CommandContext kills the direct child
+func setProcessGroup(c *exec.Cmd) {}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Named projects live under `projects:` in .dibbity.yaml. Each entry can set
+// any of the top-level keys (dbt-dir, runner, bq-project, bq-location,
+// bq-url-template, price-per-tib, state-path...) and overrides them while
+// that project is active:
+//
+//	project: warehouse
+//	projects:
+//	  warehouse:
+//	    dbt-dir: ~/code/warehouse
+//	    bq-project: data-warehouse-p
+//	  marketing:
+//	    dbt-dir: ~/code/marketing-dbt
+//	    runner: uv
+
+// ProjectNames returns the configured project names, sorted
+func ProjectNames() []string {
+	var names []string
+	for name := range viper.GetStringMap("projects") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProjectSettings returns the settings of a named project
+func ProjectSettings(name string) (map[string]any, error) {
+	projects := viper.GetStringMap("projects")
+	raw, ok := projects[strings.ToLower(name)]
+	if !ok {
+		return nil, &ConfigError{Msg: fmt.Sprintf("unknown project %q, configured projects: %s", name, strings.Join(ProjectNames(), ", "))}
+	}
+	settings, ok := raw.(map[string]any)
+	if !ok {
+		return nil, &ConfigError{Msg: fmt.Sprintf("project %q should be a map of settings", name)}
+	}
+	return settings, nil
+}
+
+// ProjectForDir returns the named project whose dbt-dir is dir, if any
+func ProjectForDir(dir string) (string, bool) {
+	for _, name := range ProjectNames() {
+		settings, err := ProjectSettings(name)
+		if err != nil {
+			continue
+		}
+		d, _ := settings["dbt-dir"].(string)
+		if d == "" {
+			continue
+		}
+		if expanded, err := ExpandHome(d); err == nil && filepath.Clean(expanded) == filepath.Clean(dir) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ActivateProject merges a named project's settings over the loaded config.
+// Flags and environment variables still take precedence since viper checks
+// them before config values.
+func ActivateProject(name string) (map[string]any, error) {
+	settings, err := ProjectSettings(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return nil, &ConfigError{Msg: "failed to apply project " + name, Err: err}
+	}
+	activeProject = name
+	return settings, nil
+}
+
+var activeProject string
+
+// ActiveProject returns the name of the active named project, or "" if none
+func ActiveProject() string {
+	return activeProject
+}
+
+// ExpandHome resolves a leading "~/" to the user's home directory
+func ExpandHome(p string) (string, error) {
+	if !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, p[2:]), nil
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DescriptionChange is a column description copied from an upstream node
+type DescriptionChange struct {
+	Node        *Node
+	Column      string
+	Description string
+	From        *Node  // upstream model or source
+	FromColumn  string // differs from Column when the column was aliased
+	Candidates  int    // upstream nodes that had a description to offer
+}
+
+// simple `[table.]column AS alias` projections
+var aliasRegex = regexp.MustCompile("(?i)`?\\b([A-Za-z_][A-Za-z0-9_]*)`?\\s+as\\s+`?([A-Za-z_][A-Za-z0-9_]*)`?")
+
+// ColumnAliases returns the simple renames in a SELECT, alias → upstream column.
+// Only plain (optionally table-qualified) columns count; expressions, function
+// calls and CAST(x AS type) are skipped.
+func ColumnAliases(sql string) map[string]string {
+	aliases := map[string]string{}
+	for _, m := range aliasRegex.FindAllStringSubmatchIndex(sql, -1) {
+		// what comes before the column must end the previous projection, or be a
+		// table qualifier, e.g. "select a.b as c" or ", b as c", not "cast(b as c"
+		before := strings.TrimRightFunc(sql[:m[0]], unicode.IsSpace)
+		if strings.HasSuffix(before, ".") {
+			before = strings.TrimRightFunc(before, func(r rune) bool {
+				return r == '_' || r == '.' || r == '`' || unicode.IsLetter(r) || unicode.IsDigit(r)
+			})
+			before = strings.TrimRightFunc(before, unicode.IsSpace)
+		}
+		lower := strings.ToLower(before)
+		if !(before == "" || strings.HasSuffix(before, ",") || strings.HasSuffix(lower, "select") || strings.HasSuffix(lower, "distinct")) {
+			continue
+		}
+		from, to := strings.ToLower(sql[m[2]:m[3]]), strings.ToLower(sql[m[4]:m[5]])
+		if from != to {
+			aliases[to] = from
+		}
+	}
+	return aliases
+}
+
+// TopoSortModels returns the models in dependency order, parents first
+func (m *Manifest) TopoSortModels() []*Node {
+	models := m.Models()
+	done := map[string]bool{}
+	var sorted []*Node
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if done[n.UniqueID] {
+			return
+		}
+		done[n.UniqueID] = true
+		for _, id := range n.DependsOn.Nodes {
+			if p, ok := m.Nodes[id]; ok && p.ResourceType == "model" {
+				visit(p)
+			}
+		}
+		sorted = append(sorted, n)
+	}
+	for _, n := range models {
+		visit(n)
+	}
+	return sorted
+}
+
+// PropagateDescriptions finds documented columns without a description whose
+// upstream models or sources describe the same column, passed through under the
+// same name or a simple alias. Models are walked parents first, so descriptions
+// flow down several levels in one go. Only nodes in only (by unique id, all when
+// nil) are changed, and descriptions only flow down through those.
+func PropagateDescriptions(m *Manifest, dbtDir string, only map[string]bool) []DescriptionChange {
+	// descriptions as they'd be after earlier changes, per node and lower-case column
+	described := map[string]map[string]string{}
+	describe := func(n *Node) map[string]string {
+		if d, ok := described[n.UniqueID]; ok {
+			return d
+		}
+		d := map[string]string{}
+		for key, c := range n.Columns {
+			name := c.Name
+			if name == "" {
+				name = key
+			}
+			if c.Description != "" {
+				d[strings.ToLower(name)] = c.Description
+			}
+		}
+		described[n.UniqueID] = d
+		return d
+	}
+
+	var changes []DescriptionChange
+	for _, n := range m.TopoSortModels() {
+		var missing []string
+		for key, c := range n.Columns {
+			if c.Description == "" {
+				name := c.Name
+				if name == "" {
+					name = key
+				}
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		sort.Strings(missing)
+
+		aliases := map[string]string{}
+		if sql, err := os.ReadFile(n.ArtefactPath(dbtDir, "compiled")); err == nil {
+			aliases = ColumnAliases(string(sql))
+		} else if sql, err := os.ReadFile(n.SourcePath(dbtDir)); err == nil {
+			aliases = ColumnAliases(string(sql))
+		}
+
+		parents := append([]string{}, n.DependsOn.Nodes...)
+		sort.Strings(parents)
+
+		own := describe(n)
+		for _, col := range missing {
+			from := strings.ToLower(col)
+			if a, ok := aliases[from]; ok {
+				from = a
+			}
+
+			var change *DescriptionChange
+			for _, id := range parents {
+				p, ok := m.Node(id)
+				if !ok {
+					continue
+				}
+				desc, ok := describe(p)[from]
+				if !ok {
+					continue
+				}
+				if change == nil {
+					change = &DescriptionChange{Node: n, Column: col, Description: desc, From: p, FromColumn: from}
+				}
+				change.Candidates++
+			}
+			if change == nil {
+				continue
+			}
+			// a node outside only keeps its empty description, so its children
+			// can't inherit through it
+			if only == nil || only[n.UniqueID] {
+				own[strings.ToLower(col)] = change.Description
+				changes = append(changes, *change)
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package core
+
+import "testing"
+
+// chainManifest has src → stg_orders → orders with only the source documented
+func chainManifest() *Manifest {
+	cols := func(desc string) map[string]*Column {
+		return map[string]*Column{"id": {Name: "id", Description: desc}}
+	}
+	node := func(id, name, kind, desc string, parents ...string) *Node {
+		n := &Node{UniqueID: id, Name: name, ResourceType: kind, Columns: cols(desc)}
+		n.DependsOn.Nodes = parents
+		return n
+	}
+	return &Manifest{
+		Nodes: map[string]*Node{
+			"model.p.stg_orders": node("model.p.stg_orders", "stg_orders", "model", "", "source.p.shop.orders"),
+			"model.p.orders":     node("model.p.orders", "orders", "model", "", "model.p.stg_orders"),
+		},
+		Sources: map[string]*Node{
+			"source.p.shop.orders": node("source.p.shop.orders", "orders", "source", "Order key"),
+		},
+	}
+}
+
+func TestPropagateDescriptions(t *testing.T) {
+	tests := []struct {
+		name string
+		only map[string]bool
+		want map[string]string // node → the upstream node it inherited from
+	}{
+		{"all", nil, map[string]string{"model.p.stg_orders": "source.p.shop.orders", "model.p.orders": "model.p.stg_orders"}},
+		{"parent only", map[string]bool{"model.p.stg_orders": true}, map[string]string{"model.p.stg_orders": "source.p.shop.orders"}},
+		{"child only", map[string]bool{"model.p.orders": true}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := PropagateDescriptions(chainManifest(), t.TempDir(), tt.only)
+			got := map[string]string{}
+			for _, c := range changes {
+				if c.Description != "Order key" || c.Column != "id" {
+					t.Errorf("change to %s = %s %q, want id %q", c.Node.UniqueID, c.Column, c.Description, "Order key")
+				}
+				got[c.Node.UniqueID] = c.From.UniqueID
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("changed %v, want %v", got, tt.want)
+			}
+			for id, from := range tt.want {
+				if got[id] != from {
+					t.Errorf("%s inherited from %q, want %q", id, got[id], from)
+				}
+			}
+		})
+	}
+}
+
+func TestColumnAliases(t *testing.T) {
+	got := ColumnAliases("select o.id as order_id, amount as total, cast(x as string) as x_str, lower(y) as y\nfrom o")
+	want := map[string]string{"order_id": "id", "total": "amount"}
+	if len(got) != len(want) {
+		t.Fatalf("ColumnAliases() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("ColumnAliases()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PullQuery builds the SELECT that extracts a table, optionally filtered and limited
+func PullQuery(project, dataset, table, where string, limit int) string {
+	query := fmt.Sprintf("SELECT *\nFROM `%s.%s.%s`", project, dataset, table)
+	if where != "" {
+		query += "\nWHERE " + where
+	}
+	if limit > 0 {
+		query += fmt.Sprintf("\nLIMIT %d", limit)
+	}
+	return query
+}
+
+// MaxPullRows caps the rows extracted per table. bq query can't page its
+// results, so bq and then dibbity hold every row in memory at once.
+const MaxPullRows = 1_000_000
+
+// ErrTooManyRows is returned by ExtractRows when a table with no limit has more than MaxPullRows rows
+var ErrTooManyRows = fmt.Errorf("more than %d rows", MaxPullRows)
+
+// ExtractRows runs query with bq and writes the rows as a JSON array to path,
+// returning how many there were. bq only prints --max_rows rows, so with no
+// limit it asks for one more than MaxPullRows and fails with ErrTooManyRows,
+// rather than writing a table that looks complete but isn't.
+func ExtractRows(ctx context.Context, query string, limit int, path string, b bool) (int, error) {
+	maxRows := min(limit, MaxPullRows)
+	if limit <= 0 {
+		maxRows = MaxPullRows + 1
+	}
+	args := append(bqGlobalArgs(), "query", "--nouse_legacy_sql", "--format=json", "--max_rows="+strconv.Itoa(maxRows))
+	LogVerbose(b, "Running: bq %s", strings.Join(args, " "))
+
+	out, stderr, err := runBq(ctx, args, query)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return 0, ctxErr
+		}
+		return 0, &BqError{Msg: "query failed: " + strings.TrimSpace(out+"\n"+stderr), Err: err}
+	}
+
+	// bq prints nothing at all for an empty result
+	if strings.TrimSpace(out) == "" {
+		out = "[]"
+	}
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		return 0, &BqError{Msg: "unexpected bq output", Err: err}
+	}
+	if len(rows) > MaxPullRows {
+		return 0, ErrTooManyRows
+	}
+	return len(rows), os.WriteFile(path, []byte(out), 0o644)
+}
+
+// DuckDBType maps a BigQuery field to the DuckDB type holding the same values
+func DuckDBType(f SchemaField) string {
+	var t string
+	switch strings.ToUpper(f.Type) {
+	case "INTEGER", "INT64":
+		t = "BIGINT"
+	case "FLOAT", "FLOAT64":
+		t = "DOUBLE"
+	case "NUMERIC":
+		t = "DECIMAL(38, 9)"
+	case "BOOLEAN", "BOOL":
+		t = "BOOLEAN"
+	case "DATE":
+		t = "DATE"
+	case "DATETIME":
+		t = "TIMESTAMP"
+	case "TIMESTAMP":
+		t = "TIMESTAMPTZ"
+	case "TIME":
+		t = "TIME"
+	case "JSON":
+		t = "JSON"
+	case "RECORD", "STRUCT":
+		parts := make([]string, len(f.Fields))
+		for i, sub := range f.Fields {
+			parts[i] = duckDBIdent(sub.Name) + " " + DuckDBType(sub)
+		}
+		t = "STRUCT(" + strings.Join(parts, ", ") + ")"
+	default:
+		// STRING, BYTES (base64), BIGNUMERIC, GEOGRAPHY, INTERVAL and RANGE come through as text
+		t = "VARCHAR"
+	}
+	if strings.EqualFold(f.Mode, "REPEATED") {
+		t += "[]"
+	}
+	return t
+}
+
+// duckDBColumns is the columns={...} argument of read_json for a schema
+func duckDBColumns(fields []SchemaField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = duckDBString(f.Name) + ": " + duckDBString(DuckDBType(f))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func duckDBIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func duckDBString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// readJSONSQL selects the rows written by ExtractRows, typed by the BigQuery schema
+func readJSONSQL(path string, fields []SchemaField) string {
+	return fmt.Sprintf("SELECT * FROM read_json(%s, format = 'array', columns = %s)", duckDBString(path), duckDBColumns(fields))
+}
+
+// DuckDBLoad loads extracted rows into schema.table of a DuckDB database,
+// replacing the table if it exists
+func DuckDBLoad(ctx context.Context, database, schema, table, rowsPath string, fields []SchemaField, b bool) error {
+	sql := fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;\nCREATE OR REPLACE TABLE %s.%s AS %s;",
+		duckDBIdent(schema), duckDBIdent(schema), duckDBIdent(table), readJSONSQL(rowsPath, fields))
+	return runDuckDB(ctx, database, sql, b)
+}
+
+// DuckDBExport writes extracted rows to a Parquet or CSV file
+func DuckDBExport(ctx context.Context, path, format, rowsPath string, fields []SchemaField, b bool) error {
+	opts := "FORMAT parquet"
+	if format == "csv" {
+		opts = "FORMAT csv, HEADER"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	sql := fmt.Sprintf("COPY (%s) TO %s (%s);", readJSONSQL(rowsPath, fields), duckDBString(path), opts)
+	return runDuckDB(ctx, ":memory:", sql, b)
+}
+
+// runDuckDB runs SQL with the duckdb CLI against a database file
+func runDuckDB(ctx context.Context, database, sql string, b bool) error {
+	LogVerbose(b, "Running duckdb %s:\n%s", database, sql)
+
+	var stderr bytes.Buffer
+	c := newCommand(ctx, "duckdb", database)
+	c.Stdin = strings.NewReader(sql)
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("duckdb failed: %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
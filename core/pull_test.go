@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExtractRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		limit   int
+		rows    int
+		maxRows string
+	}{
+		{"rows", `[{"id": 1}, {"id": 2}]`, 0, 2, "--max_rows=1000001"},
+		{"empty result", ``, 10, 0, "--max_rows=10"},
+		{"limit above the cap", `[]`, MaxPullRows + 1, 0, "--max_rows=1000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := fakeBq(t, `printf '%s' '`+tt.out+`'`)
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+
+			path := filepath.Join(t.TempDir(), "rows.json")
+			n, err := ExtractRows(context.Background(), "select 1", tt.limit, path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.rows {
+				t.Errorf("ExtractRows() = %d rows, want %d", n, tt.rows)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.out == "" && string(data) != "[]" {
+				t.Errorf("wrote %q for an empty result, want []", data)
+			}
+			args, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Contains(strings.Fields(string(args)), tt.maxRows) {
+				t.Errorf("bq called with %s, want %s", args, tt.maxRows)
+			}
+		})
+	}
+}
+
+func TestPullQuery(t *testing.T) {
+	got := PullQuery("p", "d", "t", "x > 1", 5)
+	want := "SELECT *\nFROM `p.d.t`\nWHERE x > 1\nLIMIT 5"
+	if got != want {
+		t.Fatalf("PullQuery() = %q, want %q", got, want)
+	}
+	if got := PullQuery("p", "d", "t", "", 0); strings.Contains(got, "WHERE") || strings.Contains(got, "LIMIT") {
+		t.Fatalf("PullQuery() = %q, want no filter or limit", got)
+	}
+}
+
+func TestExtractRowsTooMany(t *testing.T) {
+	// one more row than the cap, without printing a million of them
+	fakeBq(t, `printf '['; yes '{},' | head -n `+strconv.Itoa(MaxPullRows)+` | tr -d '\n'; printf '{}]'`)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join(t.TempDir(), "rows.json")
+	if _, err := ExtractRows(context.Background(), "select 1", 0, path, false); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("ExtractRows() error = %v, want ErrTooManyRows", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("ExtractRows() wrote a partial table")
+	}
+}
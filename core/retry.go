@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// RetryPolicy describes how transient bq failures are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts, including the first; 1 disables retries
+	InitialBackoff time.Duration // wait before the first retry
+	MaxBackoff     time.Duration // upper bound for any single wait
+	Multiplier     float64       // growth factor between retries
+}
+
+// RetryPolicyFromConfig reads the bq-retries, bq-retry-backoff and
+// bq-retry-max-backoff settings, falling back to sensible defaults
+func RetryPolicyFromConfig() RetryPolicy {
+	p := RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+	}
+	if viper.IsSet("bq-retries") {
+		p.MaxAttempts = viper.GetInt("bq-retries") + 1
+	}
+	if d := viper.GetDuration("bq-retry-backoff"); d > 0 {
+		p.InitialBackoff = d
+	}
+	if d := viper.GetDuration("bq-retry-max-backoff"); d > 0 {
+		p.MaxBackoff = d
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	return p
+}
+
+// Backoff returns how long to wait after the given (1-based) failed attempt.
+// The wait grows exponentially and is jittered by ±20% so parallel runs
+// don't retry in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	d *= 0.8 + 0.4*rand.Float64()
+	return time.Duration(d)
+}
+
+// Sleep waits for d or until ctx is cancelled, whichever comes first
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return contextErr(ctx)
+	}
+}
+
+// transientReasons are BigQuery error reasons that are worth retrying, see
+// https://cloud.google.com/bigquery/docs/error-messages
+var transientReasons = []string{
+	"rateLimitExceeded",
+	"backendError",
+	"internalError",
+	"jobBackendError",
+	"jobInternalError",
+}
+
+// transientMessages match network / server failures reported by bq as plain text.
+// Timeouts are deliberately not here: retrying a query that hit --bq-timeout
+// would multiply the wait the timeout is meant to cap.
+var transientMessages = []string{
+	"exceeded rate limits",
+	"service unavailable",
+	"bad gateway",
+	"connection reset",
+	"connection refused",
+	"connection aborted",
+	"broken pipe",
+	"temporary failure in name resolution",
+	"unable to connect",
+	"server disconnected",
+}
+
+var reasonRegex = regexp.MustCompile(`"reason"\s*:\s*"(\w+)"`)
+
+// ClassifyBqError inspects a failed bq response (stdout and stderr) and
+// returns a short reason and whether the failure looks transient.
+// Errors in the query itself (invalidQuery, notFound, accessDenied...) are not transient.
+func ClassifyBqError(payload string) (string, bool) {
+	// structured REST error payload, e.g. {"error": {"errors": [{"reason": "backendError"}]}}
+	if m := reasonRegex.FindStringSubmatch(payload); m != nil {
+		for _, r := range transientReasons {
+			if m[1] == r {
+				return r, true
+			}
+		}
+		return m[1], false
+	}
+
+	// bq's text output often includes the reason without JSON around it
+	for _, r := range transientReasons {
+		if strings.Contains(payload, r) {
+			return r, true
+		}
+	}
+
+	lower := strings.ToLower(payload)
+	for _, msg := range transientMessages {
+		if strings.Contains(lower, msg) {
+			return msg, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestClassifyBqError(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   string
+		reason    string
+		transient bool
+	}{
+		{"json backend error", `{"error": {"errors": [{"reason": "backendError"}]}}`, "backendError", true},
+		{"json invalid query", `{"error": {"errors": [{"reason": "invalidQuery"}]}}`, "invalidQuery", false},
+		{"text rate limit", "Exceeded rate limits: too many requests", "exceeded rate limits", true},
+		{"text reason", "BigQuery error in query operation: jobInternalError", "jobInternalError", true},
+		{"connection reset", "ConnectionResetError: connection reset by peer", "connection reset", true},
+		{"timed out", "The read operation timed out", "", false},
+		{"syntax error", "Syntax error: Unexpected keyword FROM at [1:8]", "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, transient := ClassifyBqError(tt.payload)
+			if reason != tt.reason || transient != tt.transient {
+				t.Fatalf("ClassifyBqError(%q) = %q, %t, want %q, %t", tt.payload, reason, transient, tt.reason, tt.transient)
+			}
+		})
+	}
+}
+
+// fakeBq puts a bq script on $PATH that logs its arguments on every call
+func fakeBq(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	body := "#!/bin/sh\necho call \"$@\" >> " + log + "\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "bq"), []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func calls(t *testing.T, log string) int {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "call ")
+}
+
+func TestBqDryRunDoesNotRetryTimeouts(t *testing.T) {
+	log := fakeBq(t, "sleep 5")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("bq-timeout", "100ms")
+	viper.Set("bq-retries", 3)
+	viper.Set("bq-retry-backoff", "1ms")
+
+	bq := &BqRunner{Query: "select 1"}
+	_, err := bq.BqDryRun(context.Background(), false)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("BqDryRun() error = %v, want a TimeoutError", err)
+	}
+	if n := calls(t, log); n != 1 {
+		t.Fatalf("bq was called %d times, want 1", n)
+	}
+}
+
+func TestBqDryRunRetriesTransientErrors(t *testing.T) {
+	log := fakeBq(t, `echo '{"error": {"errors": [{"reason": "backendError"}]}}'; exit 1`)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("bq-retries", 2)
+	viper.Set("bq-retry-backoff", time.Millisecond)
+
+	bq := &BqRunner{Query: "select 1"}
+	if _, err := bq.BqDryRun(context.Background(), false); err != nil {
+		t.Fatalf("BqDryRun() error = %v", err)
+	}
+	if bq.Ok {
+		t.Fatal("BqDryRun() Ok = true, want false")
+	}
+	if n := calls(t, log); n != 3 {
+		t.Fatalf("bq was called %d times, want 3", n)
+	}
+}
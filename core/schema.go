@@ -0,0 +1,270 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaField is a column of a BigQuery result or table schema
+type SchemaField struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"`
+	Mode        string        `json:"mode"` // NULLABLE, REQUIRED or REPEATED
+	Description string        `json:"description"`
+	Fields      []SchemaField `json:"fields"` // sub-fields of a RECORD / STRUCT
+}
+
+// legacyTypes maps the type names of the bq JSON schema to GoogleSQL names
+var legacyTypes = map[string]string{
+	"INTEGER": "INT64",
+	"FLOAT":   "FLOAT64",
+	"BOOLEAN": "BOOL",
+	"RECORD":  "STRUCT",
+}
+
+// SQLType returns the GoogleSQL type of the field, e.g. INT64, ARRAY<STRING>
+// or STRUCT<a INT64, b STRING>, as dbt writes data_type
+func (f SchemaField) SQLType() string {
+	t := strings.ToUpper(f.Type)
+	if legacy, ok := legacyTypes[t]; ok {
+		t = legacy
+	}
+	if t == "STRUCT" && len(f.Fields) > 0 {
+		parts := make([]string, len(f.Fields))
+		for i, sub := range f.Fields {
+			parts[i] = sub.Name + " " + sub.SQLType()
+		}
+		t = "STRUCT<" + strings.Join(parts, ", ") + ">"
+	}
+	if strings.EqualFold(f.Mode, "REPEATED") {
+		t = "ARRAY<" + t + ">"
+	}
+	return t
+}
+
+// ParseDryRunSchema returns the result schema from the JSON `bq query --dry_run` prints
+func ParseDryRunSchema(out string) ([]SchemaField, error) {
+	var raw struct {
+		Statistics struct {
+			Query struct {
+				Schema struct {
+					Fields []SchemaField `json:"fields"`
+				} `json:"schema"`
+			} `json:"query"`
+		} `json:"statistics"`
+	}
+	if err := json.Unmarshal([]byte(out), &raw); err != nil {
+		return nil, &BqError{Msg: "failed to parse dry run schema", Err: err}
+	}
+	return raw.Statistics.Query.Schema.Fields, nil
+}
+
+// FlattenSchema lists nested STRUCT fields after their parent as "parent.child",
+// the way dbt documents them
+func FlattenSchema(fields []SchemaField) []SchemaField {
+	var flat []SchemaField
+	var walk func(prefix string, fields []SchemaField)
+	walk = func(prefix string, fields []SchemaField) {
+		for _, f := range fields {
+			g := f
+			g.Name = prefix + f.Name
+			flat = append(flat, g)
+			if len(f.Fields) > 0 {
+				walk(g.Name+".", f.Fields)
+			}
+		}
+	}
+	walk("", fields)
+	return flat
+}
+
+// LiveColumns reads the columns of an existing table from INFORMATION_SCHEMA.
+// Unlike a dry run this runs a (small, metadata only) query.
+func LiveColumns(ctx context.Context, project, dataset, table string, b bool) ([]SchemaField, error) {
+	query := fmt.Sprintf("SELECT field_path, data_type, description\n"+
+		"FROM `%s.%s`.INFORMATION_SCHEMA.COLUMN_FIELD_PATHS\n"+
+		"WHERE table_name = '%s'",
+		project, dataset, strings.ReplaceAll(table, "'", "\\'"))
+
+	args := append(bqGlobalArgs(), "query", "--nouse_legacy_sql", "--format=json", "--max_rows=100000")
+	LogVerbose(b, "Reading columns of %s.%s.%s from INFORMATION_SCHEMA", project, dataset, table)
+
+	out, stderr, err := runBq(ctx, args, query)
+	if err != nil {
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, &BqError{Msg: "INFORMATION_SCHEMA query failed: " + strings.TrimSpace(out+"\n"+stderr), Err: err}
+	}
+
+	var rows []struct {
+		FieldPath   string `json:"field_path"`
+		DataType    string `json:"data_type"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		return nil, &BqError{Msg: "failed to parse INFORMATION_SCHEMA columns", Err: err}
+	}
+	if len(rows) == 0 {
+		return nil, &NotFoundError{Kind: "table", Name: fmt.Sprintf("%s.%s.%s", project, dataset, table)}
+	}
+
+	fields := make([]SchemaField, len(rows))
+	for i, r := range rows {
+		fields[i] = SchemaField{Name: r.FieldPath, Type: r.DataType, Description: r.Description}
+	}
+	return fields, nil
+}
+
+// MergedColumn is one column of a model across its documentation, the dry run
+// result schema and, optionally, the live table
+type MergedColumn struct {
+	Name        string
+	Description string
+	DocType     string // data_type in schema.yml
+	DryRunType  string
+	LiveType    string
+	Documented  bool
+	InDryRun    bool
+	InLive      bool
+}
+
+// MergeColumns lines up a model's documented columns with the dry run and live
+// schemas. Columns are matched case-insensitively, in dry run order, followed by
+// live-only and then documented-but-missing columns.
+func MergeColumns(documented map[string]*Column, dryRun []SchemaField, live []SchemaField) []MergedColumn {
+	var merged []MergedColumn
+	index := map[string]int{}
+	get := func(name string) *MergedColumn {
+		key := strings.ToLower(name)
+		if i, ok := index[key]; ok {
+			return &merged[i]
+		}
+		index[key] = len(merged)
+		merged = append(merged, MergedColumn{Name: name})
+		return &merged[len(merged)-1]
+	}
+
+	for _, f := range FlattenSchema(dryRun) {
+		c := get(f.Name)
+		c.InDryRun = true
+		c.DryRunType = f.SQLType()
+	}
+	for _, f := range live {
+		c := get(f.Name)
+		c.InLive = true
+		c.LiveType = f.SQLType()
+		if c.Description == "" {
+			c.Description = f.Description
+		}
+	}
+
+	names := make([]string, 0, len(documented))
+	for name := range documented {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		col := documented[name]
+		if col.Name != "" {
+			name = col.Name
+		}
+		c := get(name)
+		c.Documented = true
+		c.DocType = col.DataType
+		if col.Description != "" {
+			c.Description = col.Description
+		}
+	}
+	return merged
+}
+
+// typeAliases maps the type names dbt and BigQuery accept to the canonical GoogleSQL name
+var typeAliases = map[string]string{
+	"INTEGER": "INT64", "INT": "INT64", "SMALLINT": "INT64", "BIGINT": "INT64", "TINYINT": "INT64", "BYTEINT": "INT64",
+	"FLOAT": "FLOAT64", "BOOLEAN": "BOOL", "DECIMAL": "NUMERIC", "BIGDECIMAL": "BIGNUMERIC", "RECORD": "STRUCT",
+}
+
+var (
+	typeWordRegex  = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+	typeParamRegex = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// NormalizeType makes data types comparable: upper case, aliases resolved,
+// parameters like STRING(10) or NUMERIC(10, 2) and whitespace dropped
+func NormalizeType(t string) string {
+	t = typeParamRegex.ReplaceAllString(strings.ToUpper(t), "")
+	t = typeWordRegex.ReplaceAllStringFunc(t, func(w string) string {
+		if alias, ok := typeAliases[w]; ok {
+			return alias
+		}
+		return w
+	})
+	return strings.Join(strings.Fields(t), "")
+}
+
+// TypeMismatch is a column whose documented data_type differs from the output
+type TypeMismatch struct {
+	Column     string
+	Documented string
+	Actual     string
+}
+
+// ColumnDrift is how a model's documented columns differ from its output schema
+type ColumnDrift struct {
+	Missing    []string      // documented but not in the output
+	Extra      []SchemaField // top-level output columns that aren't documented
+	Mismatched []TypeMismatch
+}
+
+// Count returns the number of problems
+func (d ColumnDrift) Count() int {
+	return len(d.Missing) + len(d.Extra) + len(d.Mismatched)
+}
+
+// CompareColumns compares documented columns against an output schema. Nested
+// fields count only when documented ("parent.child"); types are compared only
+// when data_type is documented.
+func CompareColumns(documented map[string]*Column, fields []SchemaField) ColumnDrift {
+	var drift ColumnDrift
+
+	actual := map[string]SchemaField{}
+	for _, f := range FlattenSchema(fields) {
+		actual[strings.ToLower(f.Name)] = f
+	}
+	docs := map[string]*Column{}
+	for key, col := range documented {
+		name := col.Name
+		if name == "" {
+			name = key
+		}
+		docs[strings.ToLower(name)] = col
+	}
+
+	for _, f := range fields {
+		if _, ok := docs[strings.ToLower(f.Name)]; !ok {
+			drift.Extra = append(drift.Extra, f)
+		}
+	}
+
+	names := make([]string, 0, len(docs))
+	for name := range docs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		col := docs[name]
+		f, ok := actual[name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, name)
+		case col.DataType != "" && NormalizeType(col.DataType) != NormalizeType(f.SQLType()):
+			drift.Mismatched = append(drift.Mismatched, TypeMismatch{Column: f.Name, Documented: col.DataType, Actual: f.SQLType()})
+		}
+	}
+	return drift
+}
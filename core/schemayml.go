@@ -0,0 +1,477 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaFile is a dbt properties YAML file (schema.yml) opened for editing.
+// Edits go through the yaml.Node tree, but saving only splices the added or
+// changed parts into the original text, so untouched lines keep their
+// formatting and comments byte for byte.
+type SchemaFile struct {
+	Path string
+	doc  *yaml.Node
+	src  []byte // original contents, empty for a new file
+}
+
+// LoadSchemaFile opens a properties file, or starts an empty one if it doesn't exist
+func LoadSchemaFile(path string) (*SchemaFile, error) {
+	f := &SchemaFile{Path: path}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mappingNode()}}
+		setScalar(doc.Content[0], "version", "2")
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s is not a YAML mapping", path)
+	}
+	f.doc = &doc
+	if doc.Line > 0 {
+		f.src = data
+	}
+	return f, nil
+}
+
+// Model returns the mapping of a model under `models:`, adding it if create is set
+func (f *SchemaFile) Model(name string, create bool) *yaml.Node {
+	return findOrAddNamed(sequenceValue(f.doc.Content[0], "models", create), name, create)
+}
+
+// Column returns the mapping of a column under a model, adding it if create is set
+func (f *SchemaFile) Column(model *yaml.Node, name string, create bool) *yaml.Node {
+	if model == nil {
+		return nil
+	}
+	return findOrAddNamed(sequenceValue(model, "columns", create), name, create)
+}
+
+// StubColumns adds name / data_type / description stubs for fields to a model,
+// creating the model entry if needed. Existing descriptions, data types, tests
+// and comments are left alone. It returns how many columns were added.
+func (f *SchemaFile) StubColumns(model string, fields []SchemaField) int {
+	m := f.Model(model, true)
+	added := 0
+	for _, field := range fields {
+		if f.Column(m, field.Name, false) == nil {
+			added++
+		}
+		col := f.Column(m, field.Name, true)
+		SetIfEmpty(col, "data_type", strings.ToLower(field.SQLType()))
+		SetIfEmpty(col, "description", "")
+	}
+	return added
+}
+
+// Bytes returns the edited file. A new file is encoded with two-space
+// indentation; an existing one has its edits spliced into the original text.
+func (f *SchemaFile) Bytes() ([]byte, error) {
+	if len(f.src) == 0 {
+		return encodeYAML(f.doc, 0)
+	}
+
+	text := string(f.src)
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	lines := strings.SplitAfter(text, "\n")
+	lines = lines[:len(lines)-1]
+
+	// only the first document is parsed, later ones are kept as they are
+	n := nextDocument(lines, maxLine(f.doc))
+	rest := strings.Join(lines[n:], "")
+
+	sp := splicer{lines: lines[:n], starts: nodeLines(f.doc)}
+	if err := sp.collect(f.doc.Content[0]); err != nil {
+		return nil, err
+	}
+	if sp.reencode {
+		// can't splice into [a, b] / {a: b} or over a multi-line value, re-encode
+		// the document instead
+		data, err := encodeYAML(f.doc, 0)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(lines[0]) == "---" {
+			data = append([]byte("---\n"), data...)
+		}
+		return append(data, rest...), nil
+	}
+	return []byte(sp.apply() + rest), nil
+}
+
+// splicer turns the additions to a parsed yaml.Node tree into text edits.
+// Nodes added after parsing have Line 0.
+type splicer struct {
+	lines  []string
+	starts []int // sorted start lines of every parsed node
+	edits  []textEdit
+
+	// reencode is set when an edit can't be spliced into the original text
+	reencode bool
+}
+
+// textEdit replaces lines [from, to) (1-based) with text
+type textEdit struct {
+	from, to int
+	text     string
+	order    int
+}
+
+func (sp *splicer) collect(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		added := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			switch {
+			case k.Line == 0:
+				added.Content = append(added.Content, k, v)
+			case v.Line == 0:
+				// value replaced, e.g. `description:` filled in or `columns:` given items
+				if n.Style&yaml.FlowStyle != 0 || sp.valueEnd(k) > k.Line {
+					sp.reencode = true
+					return nil
+				}
+				// the key's own comment lines are still in the text
+				key := *k
+				key.HeadComment, key.FootComment = "", ""
+				pair := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{&key, v}}
+				text, err := encodeYAML(pair, k.Column-1)
+				if err != nil {
+					return err
+				}
+				sp.edits = append(sp.edits, textEdit{from: k.Line, to: k.Line + 1, text: string(text)})
+			default:
+				if err := sp.collect(v); err != nil {
+					return err
+				}
+			}
+		}
+		if len(added.Content) > 0 {
+			if n.Style&yaml.FlowStyle != 0 {
+				sp.reencode = true
+				return nil
+			}
+			text, err := encodeYAML(added, n.Content[0].Column-1)
+			if err != nil {
+				return err
+			}
+			end := sp.end(n)
+			sp.edits = append(sp.edits, textEdit{from: end + 1, to: end + 1, text: string(text)})
+		}
+	case yaml.SequenceNode:
+		added := &yaml.Node{Kind: yaml.SequenceNode}
+		var first *yaml.Node
+		for _, item := range n.Content {
+			if item.Line == 0 {
+				added.Content = append(added.Content, item)
+				continue
+			}
+			if first == nil {
+				first = item
+			}
+			if err := sp.collect(item); err != nil {
+				return err
+			}
+		}
+		if len(added.Content) > 0 {
+			if n.Style&yaml.FlowStyle != 0 || first == nil {
+				sp.reencode = true
+				return nil
+			}
+			text, err := sp.encodeItems(added, first)
+			if err != nil {
+				return err
+			}
+			end := sp.end(n)
+			sp.edits = append(sp.edits, textEdit{from: end + 1, to: end + 1, text: text})
+		}
+	}
+	return nil
+}
+
+// encodeItems encodes new sequence items laid out like the parsed item first,
+// which may be written "- name: id" or "-   name: id"
+func (sp *splicer) encodeItems(items, first *yaml.Node) (string, error) {
+	data, err := encodeYAML(items, 0)
+	if err != nil {
+		return "", err
+	}
+	dash := strings.LastIndex(sp.lines[first.Line-1][:min(first.Column-1, len(sp.lines[first.Line-1]))], "-")
+	if dash < 0 {
+		dash = max(first.Column-3, 0)
+	}
+	gap := max(first.Column-2-dash, 1)
+	pad := strings.Repeat(" ", first.Column-1)
+
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		switch {
+		case line == "":
+		case strings.TrimSpace(line) == "":
+			out.WriteString("\n")
+		case strings.HasPrefix(line, "- "):
+			out.WriteString(strings.Repeat(" ", dash) + "-" + strings.Repeat(" ", gap) + line[2:])
+		default:
+			// the item's other lines are encoded two spaces in
+			out.WriteString(pad + strings.TrimPrefix(line, "  "))
+		}
+	}
+	return out.String(), nil
+}
+
+// end returns the last line of n: the line before the next node that isn't part
+// of it, less trailing blank and comment lines
+func (sp *splicer) end(n *yaml.Node) int {
+	last := maxLine(n)
+	end := len(sp.lines)
+	if i := sort.SearchInts(sp.starts, last+1); i < len(sp.starts) {
+		end = sp.starts[i] - 1
+	}
+	for end > last {
+		t := strings.TrimSpace(sp.lines[end-1])
+		if t != "" && !strings.HasPrefix(t, "#") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// valueEnd returns the last line of the original value of the key k, which
+// continues on more deeply indented lines (a block scalar, say) or on "- " items
+// at the key's own indentation
+func (sp *splicer) valueEnd(k *yaml.Node) int {
+	end := k.Line
+	line := sp.lines[k.Line-1]
+	if i := strings.Index(line[min(k.Column-1, len(line)):], ":"); i >= 0 {
+		value := strings.TrimSpace(line[k.Column+i:])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			// a block scalar, even an empty one, owns the blank lines after it
+			end++
+		}
+	}
+	for i := k.Line; i < len(sp.lines); i++ {
+		t := strings.TrimSpace(sp.lines[i])
+		if t == "" {
+			continue
+		}
+		indent := len(sp.lines[i]) - len(strings.TrimLeft(sp.lines[i], " "))
+		if indent < k.Column-1 || indent == k.Column-1 && !strings.HasPrefix(t, "- ") && t != "-" {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// nextDocument returns the index of the first "---" line after line, or len(lines)
+func nextDocument(lines []string, line int) int {
+	for i := line; i < len(lines); i++ {
+		if t := strings.TrimRight(lines[i], " \t\r\n"); t == "---" || strings.HasPrefix(t, "--- ") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+func (sp *splicer) apply() string {
+	// apply from the bottom up so earlier line numbers stay valid; of two inserts
+	// at the same line the one collected first (the deeper node) ends up first
+	edits := sp.edits
+	for i := range edits {
+		edits[i].order = i
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].from != edits[j].from {
+			return edits[i].from > edits[j].from
+		}
+		return edits[i].order > edits[j].order
+	})
+	lines := sp.lines
+	for _, e := range edits {
+		out := append([]string{}, lines[:e.from-1]...)
+		out = append(out, e.text)
+		lines = append(out, lines[e.to-1:]...)
+	}
+	return strings.Join(lines, "")
+}
+
+// maxLine returns the last line any parsed node under n starts on
+func maxLine(n *yaml.Node) int {
+	m := n.Line
+	for _, c := range n.Content {
+		m = max(m, maxLine(c))
+	}
+	return m
+}
+
+// nodeLines returns the sorted, distinct start lines of the parsed nodes under n
+func nodeLines(n *yaml.Node) []int {
+	seen := map[int]bool{}
+	var walk func(*yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Line > 0 && n.Kind != yaml.DocumentNode {
+			seen[n.Line] = true
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(n)
+	lines := make([]int, 0, len(seen))
+	for l := range seen {
+		lines = append(lines, l)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// encodeYAML encodes n with two-space indentation, indenting every line by indent spaces
+func encodeYAML(n *yaml.Node, indent int) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if indent == 0 {
+		return buf.Bytes(), nil
+	}
+	pad := strings.Repeat(" ", indent)
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			out.WriteString(pad + line)
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// Save writes the file back
+func (f *SchemaFile) Save() error {
+	data, err := f.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, data, 0o644)
+}
+
+// FindSchemaFile returns the YAML file in dir that documents model, or
+// dir/schema.yml if none does
+func FindSchemaFile(dir string, model string) (string, bool) {
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, p := range matches {
+			f, err := LoadSchemaFile(p)
+			if err != nil {
+				continue
+			}
+			if f.Model(model, false) != nil {
+				return p, true
+			}
+		}
+	}
+	return filepath.Join(dir, "schema.yml"), false
+}
+
+// MappingValue returns the value of key in a mapping node, or nil
+func MappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// SetIfEmpty sets key to value unless the mapping already has a non-empty value for it.
+// It reports whether anything changed.
+func SetIfEmpty(m *yaml.Node, key, value string) bool {
+	if v := MappingValue(m, key); v != nil && v.Value != "" {
+		return false
+	}
+	setScalar(m, key, value)
+	return true
+}
+
+func setScalar(m *yaml.Node, key, value string) {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if value == "" {
+		// write "" rather than a bare key, which dbt reads as null
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	if v := MappingValue(m, key); v != nil {
+		*v = *node
+		return
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+}
+
+func mappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode}
+}
+
+// sequenceValue returns the sequence under key, adding an empty one if create is set
+func sequenceValue(m *yaml.Node, key string, create bool) *yaml.Node {
+	v := MappingValue(m, key)
+	if v != nil && v.Kind == yaml.SequenceNode && (len(v.Content) > 0 || !create) {
+		return v
+	}
+	if !create {
+		return nil
+	}
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	if v != nil {
+		// e.g. `columns:` with no value, or `models: []`
+		*v = *seq
+		return v
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, seq)
+	return seq
+}
+
+// findOrAddNamed finds the item with `name: <name>` in a sequence of mappings
+func findOrAddNamed(seq *yaml.Node, name string, create bool) *yaml.Node {
+	if seq == nil {
+		return nil
+	}
+	for _, item := range seq.Content {
+		if n := MappingValue(item, "name"); n != nil && strings.EqualFold(n.Value, name) {
+			return item
+		}
+	}
+	if !create {
+		return nil
+	}
+	item := mappingNode()
+	setScalar(item, "name", name)
+	seq.Content = append(seq.Content, item)
+	return item
+}
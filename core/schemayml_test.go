@@ -0,0 +1,324 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchemaFileBytes(t *testing.T) {
+	stub := func(f *SchemaFile) {
+		f.StubColumns("orders", []SchemaField{{Name: "id", Type: "INTEGER"}, {Name: "amount", Type: "NUMERIC"}})
+	}
+	describeID := func(f *SchemaFile) {
+		SetIfEmpty(f.Column(f.Model("orders", false), "id", false), "description", "Primary key")
+	}
+	tests := []struct {
+		name string
+		in   string
+		edit func(*SchemaFile)
+		want string
+	}{
+		{
+			name: "block",
+			in: `version: 2
+
+models:
+  - name: orders
+    description: Orders  # keep me
+    columns:
+      - name: id
+        description: ""
+`,
+			edit: stub,
+			want: `version: 2
+
+models:
+  - name: orders
+    description: Orders  # keep me
+    columns:
+      - name: id
+        description: ""
+        data_type: int64
+      - name: amount
+        data_type: numeric
+        description: ""
+`,
+		},
+		{
+			name: "wide item indent",
+			in: `version: 2
+models:
+-   name: orders
+    columns:
+    -   name: id
+        description: ""
+`,
+			edit: func(f *SchemaFile) {
+				stub(f)
+				f.Model("customers", true)
+			},
+			want: `version: 2
+models:
+-   name: orders
+    columns:
+    -   name: id
+        description: ""
+        data_type: int64
+    -   name: amount
+        data_type: numeric
+        description: ""
+-   name: customers
+`,
+		},
+		{
+			name: "indented items",
+			in: `version: 2
+models:
+  - name: orders
+    columns:
+      -  name: id
+         tests:
+           - unique
+`,
+			edit: stub,
+			want: `version: 2
+models:
+  - name: orders
+    columns:
+      -  name: id
+         tests:
+           - unique
+         data_type: int64
+         description: ""
+      -  name: amount
+         data_type: numeric
+         description: ""
+`,
+		},
+		{
+			name: "commented",
+			in: `# header
+version: 2
+
+models:
+  # the orders model
+  - name: orders # inline
+    columns:
+      - name: id  # pk
+        # why
+        description: ""
+
+      # trailing comment
+
+  - name: other
+`,
+			edit: describeID,
+			want: `# header
+version: 2
+
+models:
+  # the orders model
+  - name: orders # inline
+    columns:
+      - name: id  # pk
+        # why
+        description: Primary key
+
+      # trailing comment
+
+  - name: other
+`,
+		},
+		{
+			name: "flow mapping",
+			in: `version: 2
+models:
+  - name: orders
+    columns:
+      - {name: id, description: ""}
+`,
+			edit: describeID,
+			want: `version: 2
+models:
+  - name: orders
+    columns:
+      - {name: id, description: Primary key}
+`,
+		},
+		{
+			name: "flow sequence",
+			in: `version: 2
+models:
+  - name: orders
+    columns: [{name: id}]
+`,
+			edit: stub,
+			want: `version: 2
+models:
+  - name: orders
+    columns: [{name: id, data_type: int64, description: ""}, {name: amount, data_type: numeric, description: ""}]
+`,
+		},
+		{
+			name: "multi-line value",
+			in: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        description:
+          ""
+        tests: [unique]
+`,
+			edit: describeID,
+			want: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        description: Primary key
+        tests: [unique]
+`,
+		},
+		{
+			name: "empty block scalar",
+			in: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        description: |
+
+        tests: [unique]
+`,
+			edit: describeID,
+			want: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        description: Primary key
+        tests: [unique]
+`,
+		},
+		{
+			name: "multi-document",
+			in: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+---
+version: 2
+sources: []
+`,
+			edit: stub,
+			want: `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        data_type: int64
+        description: ""
+      - name: amount
+        data_type: numeric
+        description: ""
+---
+version: 2
+sources: []
+`,
+		},
+		{
+			name: "multi-document re-encoded",
+			in: `---
+version: 2
+models:
+  - {name: orders, columns: [{name: id, description: ""}]}
+---
+# another
+version: 2
+`,
+			edit: describeID,
+			want: `---
+version: 2
+models:
+  - {name: orders, columns: [{name: id, description: Primary key}]}
+---
+# another
+version: 2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.yml")
+			if err := os.WriteFile(path, []byte(tt.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := LoadSchemaFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(f)
+			got, err := f.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("Bytes() =\n%s\nwant\n%s", got, tt.want)
+			}
+			var doc yaml.Node
+			if err := yaml.Unmarshal(got, &doc); err != nil {
+				t.Fatalf("output doesn't parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchemaFileUnchanged(t *testing.T) {
+	in := "version: 2\n\nmodels:\n  - name: orders   # odd spacing kept\n    columns:\n      - name: id\n        description: \"Key\"\n"
+	path := filepath.Join(t.TempDir(), "schema.yml")
+	if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadSchemaFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SetIfEmpty(f.Column(f.Model("orders", false), "id", false), "description", "other") {
+		t.Fatal("SetIfEmpty() replaced a non-empty description")
+	}
+	got, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != in {
+		t.Fatalf("Bytes() =\n%s\nwant the file unchanged", got)
+	}
+}
+
+func TestNewSchemaFile(t *testing.T) {
+	f, err := LoadSchemaFile(filepath.Join(t.TempDir(), "schema.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.StubColumns("orders", []SchemaField{{Name: "id", Type: "STRING"}})
+	got, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `version: 2
+models:
+  - name: orders
+    columns:
+      - name: id
+        data_type: string
+        description: ""
+`
+	if string(got) != want {
+		t.Fatalf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+}